@@ -0,0 +1,218 @@
+// Command railscookie decodes, verifies and forges the cookies of a Rails app,
+// with the secrets and cookie settings read from the app like the Go server does.
+//
+//	railscookie [flags] decode [cookie]  prints the data of a cookie as JSON
+//	railscookie [flags] verify [cookie]  exits with 1 if a cookie isn't valid
+//	railscookie [flags] encode [json]    prints a cookie of the data
+//
+// The cookie or the JSON is read from stdin when it isn't an argument.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"../../config"
+	"../../session"
+)
+
+const usage = `usage: railscookie [flags] decode|verify [cookie]
+       railscookie [flags] encode [json]
+
+flags:
+`
+
+func main() {
+	railsRoot := flag.String("rails-root", "..", "Rails App Root Directory")
+	secret := flag.String("secret-key-base", "", "Secret Key Base, read from the Rails app if empty")
+	name := flag.String("name", "", "Cookie Name, the session cookie of the Rails app if empty")
+	signed := flag.Bool("signed", false, "Signed Cookie (cookies.signed) instead of an encrypted one")
+	expires := flag.Duration("expires", 0, "Expiry of the encoded cookie, the expire_after of the session store if 0")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	j, err := newJar(config.NewRails(*railsRoot), *secret, *name, *signed)
+	if err != nil {
+		fatalf("load rails config err: %v", err)
+	}
+	input, err := argOrStdin(flag.Arg(1))
+	if err != nil {
+		fatalf("read input err: %v", err)
+	}
+
+	switch flag.Arg(0) {
+	case "decode":
+		data, match, md, err := j.decode(input)
+		if match != nil {
+			printMatch(match, md)
+		}
+		if err != nil {
+			fatalf("decode err: %v", err)
+		}
+		var out bytes.Buffer
+		json.Indent(&out, data, "", "  ")
+		fmt.Println(out.String())
+	case "verify":
+		_, match, md, err := j.decode(input)
+		if match != nil {
+			printMatch(match, md)
+		}
+		if err != nil {
+			fatalf("invalid: %v", err)
+		}
+		fmt.Fprintln(os.Stderr, "valid")
+	case "encode":
+		if !json.Valid([]byte(input)) {
+			fatalf("encode err: invalid JSON")
+		}
+		if *expires == 0 {
+			*expires = j.expireAfter
+		}
+		value, err := j.encode([]byte(input), *expires)
+		if err != nil {
+			fatalf("encode err: %v", err)
+		}
+		fmt.Println(value)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// jar reads and writes one cookie of the Rails app, encrypted or signed.
+type jar struct {
+	name        string
+	signed      bool
+	expireAfter time.Duration
+	keyring     *session.Keyring
+	serializer  session.Serializer
+	metadata    bool
+}
+
+// newJar builds the keyring and the cookie settings the same way as controllers.Configure.
+func newJar(rails *config.Rails, secretKeyBase, name string, signed bool) (*jar, error) {
+	if secretKeyBase == "" {
+		var err error
+		if secretKeyBase, err = rails.SecretKeyBase(); err != nil {
+			return nil, err
+		}
+	}
+	cookies, err := rails.CookieSettings()
+	if err != nil {
+		return nil, err
+	}
+	j := &jar{
+		name:       name,
+		signed:     signed,
+		serializer: cookies.Serializer,
+		metadata:   cookies.Metadata,
+	}
+	keys := cookies.Keys(secretKeyBase)
+	j.keyring = session.NewKeyring(keys[0], keys[1:]...)
+	if name == "" || !signed {
+		store, err := rails.SessionStore()
+		if err != nil {
+			return nil, err
+		}
+		if j.name == "" {
+			j.name = store.Key
+		}
+		if j.name == store.Key {
+			j.expireAfter = store.ExpireAfter
+		}
+	}
+	return j, nil
+}
+
+// decode returns the data of a cookie as JSON, the key that matched and the
+// envelope metadata are returned even when the cookie expired or was
+// written for another purpose.
+func (j *jar) decode(value string) ([]byte, *session.Match, *session.Metadata, error) {
+	if !j.signed {
+		cs := &session.CookieStore{Keyring: j.keyring, Serializer: j.serializer}
+		cs.Options.Name = j.name
+		return cs.Decode(value)
+	}
+	data, match, err := j.keyring.Verify(value)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, md, err := session.Unwrap(data, session.Purpose(j.name))
+	if err != nil {
+		return nil, match, md, err
+	}
+	data, err = session.Deserialize(data, j.serializer)
+	return data, match, md, err
+}
+
+func (j *jar) encode(data []byte, expires time.Duration) (string, error) {
+	if j.signed {
+		var exp time.Time
+		if expires > 0 {
+			exp = time.Now().Add(expires)
+		}
+		sj := &session.SignedJar{Keyring: j.keyring, Serializer: j.serializer, Metadata: j.metadata}
+		return sj.Encode(j.name, data, exp)
+	}
+	cs := &session.CookieStore{Keyring: j.keyring, Serializer: j.serializer, Metadata: j.metadata}
+	cs.Options.Name = j.name
+	cs.Options.ExpireAfter = expires
+	return cs.Encode(data)
+}
+
+// printMatch tells on stderr which key verified the cookie and what its envelope says,
+// so stdout only has the data.
+func printMatch(match *session.Match, md *session.Metadata) {
+	key := "current"
+	if match.Deprecated() {
+		key = "rotated"
+	}
+	cipher := string(match.Cipher)
+	if cipher == "" {
+		cipher = "signed, " + string(orSHA1(match.Key.SignedDigest))
+	}
+	fmt.Fprintf(os.Stderr, "key:     #%d (%s), %s, key digest %s\n", match.Index, key, cipher, orSHA1(match.Key.Digest))
+	if md == nil {
+		fmt.Fprintln(os.Stderr, "rails:   no metadata")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "purpose: %s\n", md.Purpose)
+	if md.Expires.IsZero() {
+		fmt.Fprintln(os.Stderr, "expires: never")
+	} else {
+		fmt.Fprintf(os.Stderr, "expires: %s\n", md.Expires.Local().Format(time.RFC3339))
+	}
+}
+
+func orSHA1(d session.Digest) session.Digest {
+	if d == "" {
+		return session.SHA1
+	}
+	return d
+}
+
+func argOrStdin(arg string) (string, error) {
+	if arg != "" {
+		return arg, nil
+	}
+	in, err := ioutil.ReadAll(os.Stdin)
+	return strings.TrimSpace(string(in)), err
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "railscookie: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// App is the configuration of the Go app itself. It's read from config/app.yml,
+// keyed by environment like the config files of Rails, then overridden by the
+// environment variables and the flags, see Set.
+type App struct {
+	// Env is the Rails environment, the section of config/app.yml.
+	Env string `yaml:"-"`
+	// Port is the port of the HTTP server.
+	Port string `yaml:"port"`
+	// RailsRoot is the directory of the Rails app the secrets and settings are read from.
+	RailsRoot string `yaml:"rails_root"`
+	// RailsURL is the base URL of the Rails app the links of the emails point to, e.g.
+	// "https://example.com", the action_mailer.default_url_options of the Rails app if empty.
+	RailsURL string `yaml:"rails_url"`
+	// TrustedProxies are the CIDRs of the proxies X-Forwarded-For is read behind, Rails' defaults if empty.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	Database       Database `yaml:"database"`
+	Session        Session  `yaml:"session"`
+	SMTP           SMTP     `yaml:"smtp"`
+	Server         Server   `yaml:"server"`
+}
+
+// Database is the database of the models, the one of config/database.yml of
+// the Rails app unless the DSN is set.
+type Database struct {
+	// Driver is the name of the database/sql driver, mysql, postgres or sqlite3,
+	// or of the Rails adapter, e.g. "postgresql".
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+}
+
+// Session overrides the session settings of the Rails app.
+type Session struct {
+	// Store replaces the `config.session_store` of the Rails app if it's set.
+	Store    string `yaml:"store"`
+	RedisURL string `yaml:"redis_url"`
+	// CacheSize is the number of decrypted session cookies cached, 0 disables the cache.
+	CacheSize int           `yaml:"cache_size"`
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+}
+
+// SMTP is the server the Devise emails are sent to, they're only logged without an address.
+type SMTP struct {
+	// Address is the host:port of the server.
+	Address  string `yaml:"address"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Server are the timeouts of the HTTP server.
+type Server struct {
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// DrainDelay is how long /ready fails before the server stops accepting
+	// connections on SIGTERM or SIGINT, so the load balancer takes it out first.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// ShutdownTimeout is how long the requests in flight are waited for on SIGTERM or SIGINT.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// DefaultApp is the configuration of an environment without settings.
+var DefaultApp = App{
+	Port:      "4000",
+	RailsRoot: "..",
+	Session:   Session{CacheTTL: time.Minute},
+	Server: Server{
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		IdleTimeout:     2 * time.Minute,
+		DrainDelay:      5 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+	},
+}
+
+// AppEnvVars are the environment variables overriding the settings, by the name given to Set.
+var AppEnvVars = map[string]string{
+	"port":              "PORT",
+	"rails-root":        "RAILS_ROOT",
+	"rails-url":         "RAILS_URL",
+	"trusted-proxies":   "TRUSTED_PROXIES",
+	"database-driver":   "DATABASE_DRIVER",
+	"database-dsn":      "DATABASE_DSN",
+	"session-store":     "SESSION_STORE",
+	"redis-url":         "REDIS_URL",
+	"session-cache":     "SESSION_CACHE",
+	"session-cache-ttl": "SESSION_CACHE_TTL",
+	"smtp":              "SMTP_ADDRESS",
+	"smtp-username":     "SMTP_USERNAME",
+	"smtp-password":     "SMTP_PASSWORD",
+	"read-timeout":      "READ_TIMEOUT",
+	"write-timeout":     "WRITE_TIMEOUT",
+	"idle-timeout":      "IDLE_TIMEOUT",
+	"drain-delay":       "DRAIN_DELAY",
+	"shutdown-timeout":  "SHUTDOWN_TIMEOUT",
+}
+
+// LoadApp reads the section of the environment of RAILS_ENV in a YAML file,
+// over the DefaultApp, then applies the environment variables of AppEnvVars.
+// A missing file leaves the defaults. The file is rendered as ERB first,
+// like the YAML files of Rails.
+func LoadApp(name string) (*App, error) {
+	app := DefaultApp
+	app.Env = railsEnv()
+
+	src, err := ioutil.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := app.parse(src); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	for _, setting := range appSettings() {
+		if v := os.Getenv(AppEnvVars[setting]); v != "" {
+			if err := app.Set(setting, v); err != nil {
+				return nil, fmt.Errorf("%s: %v", AppEnvVars[setting], err)
+			}
+		}
+	}
+	return &app, nil
+}
+
+func (a *App) parse(src []byte) error {
+	src, err := renderERB(src)
+	if err != nil {
+		return err
+	}
+	// the anchors and `<<: *default` are resolved on the whole file first
+	var all map[string]interface{}
+	if err := yaml.Unmarshal(src, &all); err != nil {
+		return err
+	}
+	env, ok := all[a.Env]
+	if !ok || env == nil {
+		return nil
+	}
+	out, err := yaml.Marshal(env)
+	if err != nil {
+		return err
+	}
+	// unknown keys are errors, a typo mustn't silently leave a default
+	if err := yaml.UnmarshalStrict(out, a); err != nil {
+		return fmt.Errorf("%s: %v", a.Env, err)
+	}
+	return nil
+}
+
+func appSettings() []string {
+	names := make([]string, 0, len(AppEnvVars))
+	for name := range AppEnvVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set overrides a setting with its flag name, e.g. "session-cache-ttl", and a
+// string value: a number, a duration like "30s" or a comma separated list.
+func (a *App) Set(name, value string) error {
+	var err error
+	switch name {
+	case "port":
+		a.Port = value
+	case "rails-root":
+		a.RailsRoot = value
+	case "rails-url":
+		a.RailsURL = value
+	case "trusted-proxies":
+		a.TrustedProxies = nil
+		for _, p := range strings.Split(value, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				a.TrustedProxies = append(a.TrustedProxies, p)
+			}
+		}
+	case "database-driver":
+		a.Database.Driver = value
+	case "database-dsn":
+		a.Database.DSN = value
+	case "session-store":
+		a.Session.Store = value
+	case "redis-url":
+		a.Session.RedisURL = value
+	case "session-cache":
+		a.Session.CacheSize, err = strconv.Atoi(value)
+	case "session-cache-ttl":
+		a.Session.CacheTTL, err = time.ParseDuration(value)
+	case "smtp":
+		a.SMTP.Address = value
+	case "smtp-username":
+		a.SMTP.Username = value
+	case "smtp-password":
+		a.SMTP.Password = value
+	case "read-timeout":
+		a.Server.ReadTimeout, err = time.ParseDuration(value)
+	case "write-timeout":
+		a.Server.WriteTimeout, err = time.ParseDuration(value)
+	case "idle-timeout":
+		a.Server.IdleTimeout, err = time.ParseDuration(value)
+	case "drain-delay":
+		a.Server.DrainDelay, err = time.ParseDuration(value)
+	case "shutdown-timeout":
+		a.Server.ShutdownTimeout, err = time.ParseDuration(value)
+	default:
+		return fmt.Errorf("unknown setting %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid value %q", name, value)
+	}
+	return nil
+}
+
+// SetFlags overrides the settings with the flags of the set given on the
+// command line, the ones left to their default don't override the file and
+// the environment. The flags have the names of Set, others are ignored.
+func (a *App) SetFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if _, ok := AppEnvVars[f.Name]; !ok || err != nil {
+			return
+		}
+		if e := a.Set(f.Name, f.Value.String()); e != nil {
+			err = fmt.Errorf("flag -%s: %v", f.Name, e)
+		}
+	})
+	return err
+}
+
+// Validate checks the settings, the error lists all the invalid ones.
+func (a *App) Validate() error {
+	var problems []string
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(a.Port); err != nil || port < 1 || port > 65535 {
+		invalid("port: %q isn't a port number", a.Port)
+	}
+	if fi, err := os.Stat(a.Rails().Path("config")); err != nil || !fi.IsDir() {
+		invalid("rails_root: %q isn't a Rails app, it has no config directory", a.RailsRoot)
+	}
+	if a.RailsURL != "" {
+		if u, err := url.Parse(a.RailsURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("rails_url: %q isn't an http or https URL", a.RailsURL)
+		}
+	}
+	switch a.Database.Driver {
+	case "mysql", "mysql2", "postgres", "postgresql", "sqlite3":
+	case "":
+		if a.Database.DSN != "" {
+			invalid("database.driver is missing for the dsn")
+		}
+	default:
+		invalid("database.driver: unsupported driver %q", a.Database.Driver)
+	}
+	switch a.Session.Store {
+	case "", "cookie_store", "active_record_store", "redis_session_store":
+	default:
+		invalid("session.store: unsupported store %q", a.Session.Store)
+	}
+	if a.Session.CacheSize < 0 {
+		invalid("session.cache_size: %d is negative", a.Session.CacheSize)
+	}
+	if a.Session.CacheSize > 0 && a.Session.CacheTTL < 0 {
+		invalid("session.cache_ttl: %v is negative", a.Session.CacheTTL)
+	}
+	timeouts := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"server.read_timeout", a.Server.ReadTimeout},
+		{"server.write_timeout", a.Server.WriteTimeout},
+		{"server.idle_timeout", a.Server.IdleTimeout},
+		{"server.shutdown_timeout", a.Server.ShutdownTimeout},
+	}
+	for _, t := range timeouts {
+		if t.d <= 0 {
+			invalid("%s: %v isn't a positive duration", t.name, t.d)
+		}
+	}
+	if a.Server.DrainDelay < 0 {
+		invalid("server.drain_delay: %v is negative", a.Server.DrainDelay)
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config of the " + a.Env + " environment:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Rails returns the Rails app of the settings, in the same environment.
+func (a *App) Rails() *Rails {
+	return &Rails{Root: a.RailsRoot, Env: a.Env}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAppYAML = `
+default: &default
+  port: 4000
+  rails_root: /srv/rails
+  server:
+    read_timeout: 10s
+    write_timeout: 30s
+    idle_timeout: 2m
+    shutdown_timeout: 30s
+
+production:
+  <<: *default
+  port: 8080
+  trusted_proxies: [10.0.0.0/8]
+  session:
+    cache_size: 10000
+    cache_ttl: 1m
+`
+
+// testAppFile writes the YAML in a temporary config/app.yml, remove it with
+// os.RemoveAll(filepath.Dir(name)).
+func testAppFile(t *testing.T, src string) string {
+	dir, err := ioutil.TempDir("", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "app.yml")
+	if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// setenv sets the environment variables and returns a function restoring them.
+func setenv(vars map[string]string) func() {
+	old := map[string]*string{}
+	for k, v := range vars {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = &prev
+		} else {
+			old[k] = nil
+		}
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range old {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+func TestLoadApp(t *testing.T) {
+	name := testAppFile(t, testAppYAML)
+	defer os.RemoveAll(filepath.Dir(name))
+	defer setenv(map[string]string{"RAILS_ENV": "production"})()
+
+	app, err := LoadApp(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Env != "production" || app.Port != "8080" || app.RailsRoot != "/srv/rails" ||
+		app.Session.CacheSize != 10000 || app.Session.CacheTTL != time.Minute ||
+		!reflect.DeepEqual(app.TrustedProxies, []string{"10.0.0.0/8"}) {
+		t.Errorf("LoadApp = %+v", app)
+	}
+	// the settings missing from the file keep their default
+	if app.Server.DrainDelay != DefaultApp.Server.DrainDelay {
+		t.Errorf("drain_delay = %v, want the default %v", app.Server.DrainDelay, DefaultApp.Server.DrainDelay)
+	}
+
+	// the environment variables override the file
+	defer setenv(map[string]string{
+		"PORT":              "9090",
+		"TRUSTED_PROXIES":   "192.168.0.0/16, 172.16.0.0/12",
+		"SESSION_CACHE_TTL": "30s",
+		"DATABASE_DSN":      "",
+	})()
+	app, err = LoadApp(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Port != "9090" || app.Session.CacheTTL != 30*time.Second || app.Session.CacheSize != 10000 ||
+		!reflect.DeepEqual(app.TrustedProxies, []string{"192.168.0.0/16", "172.16.0.0/12"}) {
+		t.Errorf("LoadApp with the environment = %+v", app)
+	}
+
+	// the environment without a section and a missing file have the defaults
+	os.Setenv("RAILS_ENV", "staging")
+	os.Unsetenv("PORT")
+	if app, err := LoadApp(name); err != nil || app.Port != DefaultApp.Port || app.RailsRoot != DefaultApp.RailsRoot {
+		t.Errorf("LoadApp of staging = %+v, %v", app, err)
+	}
+	if app, err := LoadApp(filepath.Join(filepath.Dir(name), "missing.yml")); err != nil || app.Port != DefaultApp.Port {
+		t.Errorf("LoadApp of a missing file = %+v, %v", app, err)
+	}
+}
+
+func TestLoadAppErrors(t *testing.T) {
+	defer setenv(map[string]string{"RAILS_ENV": "production"})()
+	tests := []struct {
+		name string
+		src  string
+		env  map[string]string
+		err  string
+	}{
+		{"unknown key", "production:\n  prot: 8080\n", nil, "field prot not found"},
+		{"bad duration", "production:\n  server:\n    read_timeout: soon\n", nil, "cannot unmarshal !!str `soon` into time.Duration"},
+		{"bad yaml", "production: [\n", nil, "app.yml"},
+		{"bad env number", "", map[string]string{"SESSION_CACHE": "many"}, `SESSION_CACHE: session-cache: invalid value "many"`},
+		{"bad env duration", "", map[string]string{"DRAIN_DELAY": "5"}, `DRAIN_DELAY: drain-delay: invalid value "5"`},
+	}
+	for _, tt := range tests {
+		name := testAppFile(t, tt.src)
+		restore := setenv(tt.env)
+		_, err := LoadApp(name)
+		restore()
+		os.RemoveAll(filepath.Dir(name))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: LoadApp err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestAppSetFlags(t *testing.T) {
+	name := testAppFile(t, testAppYAML)
+	defer os.RemoveAll(filepath.Dir(name))
+	defer setenv(map[string]string{"RAILS_ENV": "production", "PORT": "9090", "SESSION_CACHE": "50"})()
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.String("config", "config/app.yml", "")
+	fs.String("port", DefaultApp.Port, "")
+	fs.String("rails-root", DefaultApp.RailsRoot, "")
+	fs.Int("session-cache", DefaultApp.Session.CacheSize, "")
+	fs.Duration("shutdown-timeout", DefaultApp.Server.ShutdownTimeout, "")
+	if err := fs.Parse([]string{"-config", name, "-port", "3000", "-shutdown-timeout", "1m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := LoadApp(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+	// the given flags override the environment and the file, the others
+	// don't override them with their defaults
+	if app.Port != "3000" || app.Server.ShutdownTimeout != time.Minute {
+		t.Errorf("flags not applied: port %q, shutdown_timeout %v", app.Port, app.Server.ShutdownTimeout)
+	}
+	if app.RailsRoot != "/srv/rails" || app.Session.CacheSize != 50 {
+		t.Errorf("flag defaults applied: rails_root %q, cache_size %d", app.RailsRoot, app.Session.CacheSize)
+	}
+
+	fs = flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.String("read-timeout", "", "")
+	if err := fs.Parse([]string{"-read-timeout", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetFlags(fs); err == nil || !strings.Contains(err.Error(), "flag -read-timeout") {
+		t.Errorf("SetFlags with an invalid duration err = %v", err)
+	}
+}
+
+func TestAppValidate(t *testing.T) {
+	rails := testRails(t, "production", map[string]string{"config/application.rb": ""})
+	defer os.RemoveAll(rails.Root)
+	valid := func() App {
+		app := DefaultApp
+		app.Env, app.RailsRoot = "production", rails.Root
+		return app
+	}
+	if app := valid(); app.Validate() != nil {
+		t.Fatalf("Validate of the defaults err: %v", app.Validate())
+	}
+
+	tests := []struct {
+		name   string
+		change func(*App)
+		err    string
+	}{
+		{"port", func(a *App) { a.Port = "http" }, `port: "http" isn't a port number`},
+		{"port range", func(a *App) { a.Port = "70000" }, `port: "70000" isn't a port number`},
+		{"rails root", func(a *App) { a.RailsRoot = filepath.Join(rails.Root, "missing") }, "isn't a Rails app, it has no config directory"},
+		{"rails url", func(a *App) { a.RailsURL = "example.com" }, `rails_url: "example.com" isn't an http or https URL`},
+		{"rails url scheme", func(a *App) { a.RailsURL = "ftp://example.com" }, `rails_url: "ftp://example.com" isn't an http or https URL`},
+		{"driver", func(a *App) { a.Database.Driver = "oracle" }, `database.driver: unsupported driver "oracle"`},
+		{"dsn without driver", func(a *App) { a.Database.DSN = "root@/shop" }, "database.driver is missing for the dsn"},
+		{"session store", func(a *App) { a.Session.Store = "mem_cache_store" }, `session.store: unsupported store "mem_cache_store"`},
+		{"cache size", func(a *App) { a.Session.CacheSize = -1 }, "session.cache_size: -1 is negative"},
+		{"cache ttl", func(a *App) { a.Session.CacheSize, a.Session.CacheTTL = 10, -time.Second }, "session.cache_ttl: -1s is negative"},
+		{"read timeout", func(a *App) { a.Server.ReadTimeout = 0 }, "server.read_timeout: 0s isn't a positive duration"},
+		{"write timeout", func(a *App) { a.Server.WriteTimeout = -time.Second }, "server.write_timeout: -1s isn't a positive duration"},
+		{"idle timeout", func(a *App) { a.Server.IdleTimeout = 0 }, "server.idle_timeout: 0s isn't a positive duration"},
+		{"shutdown timeout", func(a *App) { a.Server.ShutdownTimeout = 0 }, "server.shutdown_timeout: 0s isn't a positive duration"},
+		{"drain delay", func(a *App) { a.Server.DrainDelay = -time.Second }, "server.drain_delay: -1s is negative"},
+	}
+	for _, tt := range tests {
+		app := valid()
+		tt.change(&app)
+		err := app.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: Validate err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+
+	// all the invalid settings are listed
+	app := valid()
+	app.Port, app.Session.Store = "0", "memory"
+	err := app.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid config of the production environment:\n") ||
+		!strings.Contains(err.Error(), "port:") || !strings.Contains(err.Error(), "session.store:") {
+		t.Errorf("Validate err = %v, want both settings", err)
+	}
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"../session"
+)
+
+// CookieSettings are the settings of the Rails encrypted cookie jar.
+type CookieSettings struct {
+	// LoadDefaults is the version passed to `config.load_defaults`, 0 if not called.
+	LoadDefaults float64
+	Serializer   session.Serializer
+	// Cipher is the cipher new cookies are written with.
+	Cipher session.Cipher
+	Digest session.Digest
+	// Metadata is `use_cookies_with_metadata`, whether cookies are written in the `_rails` envelope.
+	Metadata          bool
+	Salt              string
+	SignSalt          string
+	AuthenticatedSalt string
+	SignedSalt        string
+	// SignedDigest is the HMAC digest of the signed cookies.
+	SignedDigest session.Digest
+
+	metadataSet bool
+}
+
+var (
+	rubyLoadDefaults = regexp.MustCompile(`config\.load_defaults\s*\(?\s*["']?([\d.]+)`)
+	rubyConfigAssign = regexp.MustCompile(`config\.(?:action_dispatch|active_support)\.(\w+)\s*=\s*(.+?)\s*$`)
+)
+
+// CookieSettings reads the cookie jar settings from config/application.rb,
+// config/environments/<env>.rb and config/initializers/*.rb, in the order Rails
+// loads them. Settings that aren't found get the defaults of `config.load_defaults`.
+func (r *Rails) CookieSettings() (*CookieSettings, error) {
+	files, err := r.configFiles()
+	if err != nil {
+		return nil, err
+	}
+	cs := &CookieSettings{
+		Salt:              session.EncryptedCookieSalt,
+		SignSalt:          session.EncryptedSignedCookieSalt,
+		AuthenticatedSalt: session.AuthenticatedEncryptedCookieSalt,
+		SignedSalt:        session.SignedCookieSalt,
+		SignedDigest:      session.SHA1,
+	}
+	for _, name := range files {
+		if err := cs.readRuby(name); err != nil {
+			return nil, err
+		}
+	}
+	if cs.Digest == "" {
+		cs.Digest = session.SHA1
+		if cs.LoadDefaults >= 7.0 {
+			cs.Digest = session.SHA256
+		}
+	}
+	if cs.Cipher == "" {
+		cs.Cipher = session.CBC
+		if cs.LoadDefaults >= 5.2 {
+			cs.Cipher = session.GCM
+		}
+	}
+	if !cs.metadataSet {
+		cs.Metadata = cs.LoadDefaults >= 6.0
+	}
+	if cs.Serializer == "" {
+		cs.Serializer = session.MarshalSerializer
+		if cs.LoadDefaults >= 7.0 {
+			cs.Serializer = session.JSONSerializer
+		}
+	}
+	return cs, nil
+}
+
+// configFiles returns the Ruby files configuring the app, in the order Rails loads them.
+func (r *Rails) configFiles() ([]string, error) {
+	files := []string{r.Path("config", "application.rb"), r.Path("config", "environments", r.Env+".rb")}
+	initializers, err := filepath.Glob(r.Path("config", "initializers", "*.rb"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(initializers)
+	return append(files, initializers...), nil
+}
+
+// Key returns the session key for the secret_key_base with these settings.
+func (cs *CookieSettings) Key(secretKeyBase string) session.Key {
+	return session.Key{
+		SecretKeyBase:     secretKeyBase,
+		Cipher:            cs.Cipher,
+		Digest:            cs.Digest,
+		Salt:              cs.Salt,
+		SignSalt:          cs.SignSalt,
+		AuthenticatedSalt: cs.AuthenticatedSalt,
+		SignedSalt:        cs.SignedSalt,
+		SignedDigest:      cs.SignedDigest,
+	}
+}
+
+// Keys returns the keys Rails reads cookies with, the current key first.
+// Like Rails, apps with authenticated encryption still read the legacy CBC cookies.
+func (cs *CookieSettings) Keys(secretKeyBase string) []session.Key {
+	key := cs.Key(secretKeyBase)
+	keys := []session.Key{key}
+	if key.Cipher == session.GCM {
+		legacy := key
+		legacy.Cipher = session.CBC
+		keys = append(keys, legacy)
+	}
+	return keys
+}
+
+// readRuby picks the cookie settings out of a Ruby config file line by line.
+// A missing file is ignored.
+func (cs *CookieSettings) readRuby(name string) error {
+	return scanRuby(name, func(line string) {
+		if m := rubyLoadDefaults.FindStringSubmatch(line); m != nil {
+			cs.LoadDefaults, _ = strconv.ParseFloat(m[1], 64)
+			return
+		}
+		m := rubyConfigAssign.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		val := rubyValue(m[2])
+		switch m[1] {
+		case "cookies_serializer":
+			cs.Serializer = session.Serializer(val)
+		case "encrypted_cookie_cipher":
+			cs.Cipher = session.Cipher(strings.ToLower(val))
+		case "use_authenticated_cookie_encryption":
+			if val != "true" {
+				cs.Cipher = session.CBC
+			} else if cs.Cipher == "" || cs.Cipher == session.CBC {
+				cs.Cipher = session.GCM
+			}
+		case "key_generator_hash_digest_class":
+			if strings.HasSuffix(val, "SHA256") {
+				cs.Digest = session.SHA256
+			} else if strings.HasSuffix(val, "SHA1") {
+				cs.Digest = session.SHA1
+			}
+		case "use_cookies_with_metadata":
+			cs.Metadata, cs.metadataSet = val == "true", true
+		case "encrypted_cookie_salt":
+			cs.Salt = val
+		case "encrypted_signed_cookie_salt":
+			cs.SignSalt = val
+		case "authenticated_encrypted_cookie_salt":
+			cs.AuthenticatedSalt = val
+		case "signed_cookie_salt":
+			cs.SignedSalt = val
+		case "signed_cookie_digest":
+			cs.SignedDigest = session.Digest(strings.ToUpper(val))
+		}
+	})
+}
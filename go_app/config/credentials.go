@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"../session"
+)
+
+// ErrNoMasterKey is returned when the credentials exist but there's no key to decrypt them.
+var ErrNoMasterKey = errors.New("config: missing RAILS_MASTER_KEY or config/master.key")
+
+// Credentials decrypts the encrypted credentials of the environment,
+// config/credentials/<env>.yml.enc if it exists or else config/credentials.yml.enc.
+// It returns nil without error when the app has no credentials.
+func (r *Rails) Credentials() (map[string]interface{}, error) {
+	name, keyName := "config/credentials/"+r.Env+".yml.enc", "config/credentials/"+r.Env+".key"
+	if _, err := os.Stat(r.Path(name)); os.IsNotExist(err) {
+		name, keyName = "config/credentials.yml.enc", "config/master.key"
+	}
+	content, err := ioutil.ReadFile(r.Path(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := r.masterKey(keyName)
+	if err != nil {
+		return nil, err
+	}
+	src, err := decryptEncryptedFile(string(content), key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	m, err := parseYAML(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return m, nil
+}
+
+// masterKey reads the key from RAILS_MASTER_KEY or from the key file, like Rails does.
+func (r *Rails) masterKey(keyName string) ([]byte, error) {
+	key := os.Getenv("RAILS_MASTER_KEY")
+	if key == "" {
+		b, err := ioutil.ReadFile(r.Path(keyName))
+		if os.IsNotExist(err) {
+			return nil, ErrNoMasterKey
+		}
+		if err != nil {
+			return nil, err
+		}
+		key = string(b)
+	}
+	raw, err := hex.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, errors.New("config: the master key isn't hex encoded")
+	}
+	return raw, nil
+}
+
+// decryptEncryptedFile is the equivalent of ActiveSupport::EncryptedFile#read:
+// the content is aes-128-gcm encrypted and the plain text Marshal dumped.
+func decryptEncryptedFile(content string, key []byte) ([]byte, error) {
+	data, err := session.DecryptGCM(strings.TrimSpace(content), key)
+	if err != nil {
+		return nil, err
+	}
+	v, err := session.UnmarshalRuby(data)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("unexpected content")
+	}
+	return []byte(s), nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+// The credentials are encrypted like ActiveSupport::EncryptedFile writes them,
+// aes-128-gcm of the Marshal dumped YAML, with a fixed IV.
+const (
+	testMasterKey         = "00112233445566778899aabbccddeeff"
+	testCredentials       = "4o5nvDDSH2ggVEKGuxHBTslIvNR8VyxitLXd/pnvRy7FneZEPoY8329PvVS6aJLxtoYcvHimVXLQjYQckWpZ446T9h6sHGcdyyJE63MshQ/SRwm0uVrxrPue1Sm9v62z5h9jna5A9Ji9a53fS2gC6NI1kCMEwXVHfwPzG+/VizHgiu6YOs3ub0W19xAEnqAMfMx+UFyPw08YIOj1iUSyBXXyI6ftd4H+ULybmDh05xIoVFF3sE6ung==--AQIDBAUGBwgJCgsM--JUqEgmMWffR9ztigz39OCQ=="
+	testCredentialsSecret = "5c9cf2d1e0b0c4a7f3e1d2c6b8a9f0e1d3c5b7a9e2f4d6c8b0a1e3f5d7c9b2a4e6f8d0c1b3a5e7f9d2c4b6a8e0f1d3c5b7a9e2f4d6c8b0a1e3f5d7c9b2a4e6f8"
+
+	// config/credentials/staging.yml.enc
+	testStagingKey         = "ffeeddccbbaa99887766554433221100"
+	testStagingCredentials = "wV5nInv4rLzLIzSXD8IY9X03C8FGMa5+VH97EiN5T8kuXrDatAjKT8XuuHPEcNIQr7/IEm8rf8kMWbVxmevGG5oRIHeDGQk=--AQIDBAUGBwgJCgsM--9SMey1h034bOwPSFUfneww=="
+)
+
+func TestCredentials(t *testing.T) {
+	os.Unsetenv("RAILS_MASTER_KEY")
+	rails := testRails(t, "production", map[string]string{
+		"config/credentials.yml.enc": testCredentials + "\n",
+		"config/master.key":          testMasterKey + "\n",
+	})
+	defer os.RemoveAll(rails.Root)
+
+	creds, err := rails.Credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := stringValue(creds, "secret_key_base"); s != testCredentialsSecret {
+		t.Errorf("secret_key_base = %q, want %q", s, testCredentialsSecret)
+	}
+	if aws, _ := creds["aws"].(map[string]interface{}); stringValue(aws, "bucket") != "shop-assets" {
+		t.Errorf("aws = %#v, want the bucket shop-assets", creds["aws"])
+	}
+
+	// RAILS_MASTER_KEY takes precedence over the key file
+	os.Setenv("RAILS_MASTER_KEY", testStagingKey)
+	_, err = rails.Credentials()
+	os.Unsetenv("RAILS_MASTER_KEY")
+	if err == nil {
+		t.Errorf("Credentials with the wrong RAILS_MASTER_KEY err = nil")
+	}
+	os.Remove(rails.Path("config/master.key"))
+	os.Setenv("RAILS_MASTER_KEY", testMasterKey)
+	creds, err = rails.Credentials()
+	os.Unsetenv("RAILS_MASTER_KEY")
+	if err != nil || stringValue(creds, "secret_key_base") != testCredentialsSecret {
+		t.Errorf("Credentials with RAILS_MASTER_KEY = %v, %v", creds, err)
+	}
+
+	if _, err := rails.Credentials(); err != ErrNoMasterKey {
+		t.Errorf("Credentials without a key err = %v, want %v", err, ErrNoMasterKey)
+	}
+}
+
+func TestCredentialsEnvironment(t *testing.T) {
+	os.Unsetenv("RAILS_MASTER_KEY")
+	files := map[string]string{
+		"config/credentials.yml.enc":         testCredentials,
+		"config/master.key":                  testMasterKey,
+		"config/credentials/staging.yml.enc": testStagingCredentials,
+		"config/credentials/staging.key":     testStagingKey,
+	}
+	rails := testRails(t, "staging", files)
+	defer os.RemoveAll(rails.Root)
+	creds, err := rails.Credentials()
+	if err != nil || stringValue(creds, "secret_key_base") != "staging-secret-from-the-staging-credentials" {
+		t.Errorf("staging Credentials = %v, %v", creds, err)
+	}
+
+	// the other environments use the default credentials
+	rails.Env = "production"
+	creds, err = rails.Credentials()
+	if err != nil || stringValue(creds, "secret_key_base") != testCredentialsSecret {
+		t.Errorf("production Credentials = %v, %v", creds, err)
+	}
+}
+
+func TestCredentialsInvalid(t *testing.T) {
+	os.Unsetenv("RAILS_MASTER_KEY")
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"wrong key", map[string]string{"config/credentials.yml.enc": testCredentials, "config/master.key": testStagingKey}},
+		{"key not hex", map[string]string{"config/credentials.yml.enc": testCredentials, "config/master.key": "not a key"}},
+		{"tampered", map[string]string{"config/credentials.yml.enc": "A" + testCredentials[1:], "config/master.key": testMasterKey}},
+		{"not encrypted", map[string]string{"config/credentials.yml.enc": "secret_key_base: abc", "config/master.key": testMasterKey}},
+	}
+	for _, tt := range tests {
+		rails := testRails(t, "production", tt.files)
+		if _, err := rails.Credentials(); err == nil {
+			t.Errorf("%s: Credentials err = nil", tt.name)
+		}
+		os.RemoveAll(rails.Root)
+	}
+
+	rails := testRails(t, "production", nil)
+	defer os.RemoveAll(rails.Root)
+	if creds, err := rails.Credentials(); creds != nil || err != nil {
+		t.Errorf("Credentials without the file = %v, %v, want nil", creds, err)
+	}
+}
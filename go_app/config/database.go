@@ -0,0 +1,17 @@
+package config
+
+// Database returns the section of config/database.yml for the environment,
+// with its ERB rendered and `<<: *default` merged. With the three-tier
+// config of Rails 6 it's the primary database. It returns nil without error
+// when the app has no config/database.yml, e.g. with only DATABASE_URL.
+func (r *Rails) Database() (map[string]interface{}, error) {
+	all, err := r.readYAML("config/database.yml")
+	if err != nil {
+		return nil, err
+	}
+	db := section(all, r.Env)
+	if primary := section(db, "primary"); primary != nil {
+		return primary, nil
+	}
+	return db, nil
+}
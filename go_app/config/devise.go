@@ -0,0 +1,135 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"../session"
+)
+
+// Devise are the settings of config/initializers/devise.rb the Go app shares with Rails.
+type Devise struct {
+	// Stretches is the bcrypt cost of new passwords.
+	Stretches int
+	// Pepper is appended to the passwords before hashing them.
+	Pepper string
+	// CaseInsensitiveKeys and StripWhitespaceKeys are the authentication keys,
+	// e.g. "email", that are downcased and stripped before looking up a user.
+	CaseInsensitiveKeys []string
+	StripWhitespaceKeys []string
+	// MinPasswordLength and MaxPasswordLength are the bounds of `password_length`.
+	MinPasswordLength int
+	MaxPasswordLength int
+
+	// SecretKey is the secret the tokens are derived from, Devise uses the
+	// secret_key_base when it isn't set in the initializer.
+	SecretKey string
+	// KeyDigest is the digest of the key generator of the tokens,
+	// the one set by `key_generator_hash_digest_class` in Rails 7.
+	KeyDigest session.Digest
+	// MailerSender is the From address of the Devise emails.
+	MailerSender string
+	// ResetPasswordWithin is how long a reset password token stays valid.
+	ResetPasswordWithin time.Duration
+	// SignInAfterResetPassword signs the user in after resetting the password.
+	SignInAfterResetPassword bool
+	// Paranoid hides whether an email is registered.
+	Paranoid bool
+
+	// RememberFor is how long the remember cookie signs a user in.
+	RememberFor time.Duration
+	// ExtendRememberPeriod renews the remember cookie whenever it signs a user in.
+	ExtendRememberPeriod bool
+	// ExpireAllRememberMeOnSignOut invalidates the remember cookies of all the
+	// browsers of a user when it signs out.
+	ExpireAllRememberMeOnSignOut bool
+}
+
+// DefaultDevise returns the defaults of the Devise gem.
+func DefaultDevise() Devise {
+	return Devise{
+		Stretches:                11,
+		CaseInsensitiveKeys:      []string{"email"},
+		StripWhitespaceKeys:      []string{"email"},
+		MinPasswordLength:        6,
+		MaxPasswordLength:        128,
+		KeyDigest:                session.SHA1,
+		MailerSender:             "please-change-me-at-config-initializers-devise@example.com",
+		ResetPasswordWithin:      6 * time.Hour,
+		SignInAfterResetPassword: true,
+
+		RememberFor:                  14 * 24 * time.Hour,
+		ExpireAllRememberMeOnSignOut: true,
+	}
+}
+
+var (
+	rubyDeviseAssign = regexp.MustCompile(`^config\.(\w+)\s*=\s*(.+?)\s*$`)
+	rubySymbolList   = regexp.MustCompile(`:(\w+)`)
+	rubyRange        = regexp.MustCompile(`^(\d+)\.\.(\d+)$`)
+)
+
+// Devise reads the Devise settings from config/initializers/devise.rb,
+// the settings that aren't found keep the Devise defaults.
+func (r *Rails) Devise() (*Devise, error) {
+	d := DefaultDevise()
+	err := scanRuby(r.Path("config", "initializers", "devise.rb"), func(line string) {
+		m := rubyDeviseAssign.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		val := r.rubyEnvValue(m[2])
+		switch m[1] {
+		case "stretches":
+			if n, err := strconv.Atoi(val); err == nil {
+				d.Stretches = n
+			}
+		case "pepper":
+			d.Pepper = val
+		case "case_insensitive_keys":
+			d.CaseInsensitiveKeys = rubySymbols(val)
+		case "strip_whitespace_keys":
+			d.StripWhitespaceKeys = rubySymbols(val)
+		case "password_length":
+			if m := rubyRange.FindStringSubmatch(val); m != nil {
+				d.MinPasswordLength, _ = strconv.Atoi(m[1])
+				d.MaxPasswordLength, _ = strconv.Atoi(m[2])
+			}
+		case "secret_key":
+			d.SecretKey = val
+		case "mailer_sender":
+			d.MailerSender = val
+		case "reset_password_within":
+			if dur, ok := rubyDuration(val); ok {
+				d.ResetPasswordWithin = dur
+			}
+		case "sign_in_after_reset_password":
+			d.SignInAfterResetPassword = val == "true"
+		case "paranoid":
+			d.Paranoid = val == "true"
+		case "remember_for":
+			if dur, ok := rubyDuration(val); ok {
+				d.RememberFor = dur
+			}
+		case "extend_remember_period":
+			d.ExtendRememberPeriod = val == "true"
+		case "expire_all_remember_me_on_sign_out":
+			d.ExpireAllRememberMeOnSignOut = val == "true"
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// rubySymbols parses an array of symbols like `[:email]`.
+func rubySymbols(s string) []string {
+	var res []string
+	for _, m := range rubySymbolList.FindAllStringSubmatch(strings.Trim(s, "[]"), -1) {
+		res = append(res, m[1])
+	}
+	return res
+}
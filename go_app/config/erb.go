@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	erbTag = regexp.MustCompile(`(?s)<%(=|#)?(.*?)-?%>`)
+
+	// ENV["NAME"] || "default"
+	erbEnvIndex = regexp.MustCompile(`^ENV\[\s*["'](\w+)["']\s*\](?:\s*\|\|\s*(.+))?$`)
+	// ENV.fetch("NAME"), ENV.fetch("NAME", "default") or ENV.fetch("NAME") { "default" }
+	erbEnvFetch = regexp.MustCompile(`^ENV\.fetch\(\s*["'](\w+)["']\s*(?:,\s*(.+?)\s*)?\)(?:\s*\{\s*(.+?)\s*\})?$`)
+	erbString   = regexp.MustCompile(`^"([^"]*)"$|^'([^']*)'$`)
+	erbNumber   = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+)
+
+// renderERB evaluates the ERB tags of Rails' YAML files. Only the expressions
+// those files commonly contain are supported: reading environment variables
+// with ENV[] or ENV.fetch, with an optional default, and literals.
+func renderERB(src []byte) ([]byte, error) {
+	var firstErr error
+	out := erbTag.ReplaceAllFunc(src, func(tag []byte) []byte {
+		m := erbTag.FindSubmatch(tag)
+		switch string(m[1]) {
+		case "#":
+			return nil
+		case "=":
+			v, err := evalERB(strings.TrimSpace(string(m[2])))
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			return []byte(v)
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("unsupported ERB code %q", strings.TrimSpace(string(m[2])))
+		}
+		return nil
+	})
+	return out, firstErr
+}
+
+func evalERB(expr string) (string, error) {
+	if m := erbEnvIndex.FindStringSubmatch(expr); m != nil {
+		if v := os.Getenv(m[1]); v != "" || m[2] == "" {
+			return v, nil
+		}
+		return evalERB(strings.TrimSpace(m[2]))
+	}
+	if m := erbEnvFetch.FindStringSubmatch(expr); m != nil {
+		if v, ok := os.LookupEnv(m[1]); ok {
+			return v, nil
+		}
+		switch {
+		case m[2] != "":
+			return evalERB(m[2])
+		case m[3] != "":
+			return evalERB(m[3])
+		}
+		return "", fmt.Errorf("key not found: %q", m[1])
+	}
+	if m := erbString.FindStringSubmatch(expr); m != nil {
+		return m[1] + m[2], nil
+	}
+	if erbNumber.MatchString(expr) {
+		return expr, nil
+	}
+	return "", fmt.Errorf("unsupported ERB expression %q", expr)
+}
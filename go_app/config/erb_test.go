@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRenderERB(t *testing.T) {
+	os.Setenv("ERB_TEST_SECRET", "5c9cf2d1e0b0c4a7")
+	os.Setenv("ERB_TEST_EMPTY", "")
+	defer os.Unsetenv("ERB_TEST_SECRET")
+	defer os.Unsetenv("ERB_TEST_EMPTY")
+	os.Unsetenv("ERB_TEST_MISSING")
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"env", `secret_key_base: <%= ENV["ERB_TEST_SECRET"] %>`, "secret_key_base: 5c9cf2d1e0b0c4a7"},
+		{"env single quotes", `secret_key_base: <%= ENV['ERB_TEST_SECRET'] %>`, "secret_key_base: 5c9cf2d1e0b0c4a7"},
+		{"missing env", `secret_key_base: <%= ENV["ERB_TEST_MISSING"] %>`, "secret_key_base: "},
+		{"env or default", `pool: <%= ENV["ERB_TEST_MISSING"] || 5 %>`, "pool: 5"},
+		{"empty env or default", `host: <%= ENV["ERB_TEST_EMPTY"] || "localhost" %>`, "host: localhost"},
+		{"fetch", `key: <%= ENV.fetch("ERB_TEST_SECRET") %>`, "key: 5c9cf2d1e0b0c4a7"},
+		{"fetch empty", `key: <%= ENV.fetch("ERB_TEST_EMPTY", "default") %>`, "key: "},
+		{"fetch default", `pool: <%= ENV.fetch("ERB_TEST_MISSING", 5) %>`, "pool: 5"},
+		{"fetch block", `pool: <%= ENV.fetch("ERB_TEST_MISSING") { 10 } %>`, "pool: 10"},
+		{"nested default", `key: <%= ENV.fetch("ERB_TEST_MISSING") { ENV["ERB_TEST_SECRET"] } %>`, "key: 5c9cf2d1e0b0c4a7"},
+		{"comment", "a: 1<%# a comment %>\nb: <%= 'x' -%>", "a: 1\nb: x"},
+		{"multiline", "a: <%=\n  ENV['ERB_TEST_SECRET']\n%>", "a: 5c9cf2d1e0b0c4a7"},
+	}
+	for _, tt := range tests {
+		got, err := renderERB([]byte(tt.src))
+		if err != nil {
+			t.Errorf("%s: renderERB err: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: renderERB = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderERBUnsupported(t *testing.T) {
+	for _, src := range []string{
+		`key: <%= ENV.fetch("ERB_TEST_MISSING") %>`,
+		`key: <%= Rails.application.credentials.secret_key_base %>`,
+		"<% if true %>a: 1<% end %>",
+	} {
+		if _, err := renderERB([]byte(src)); err == nil {
+			t.Errorf("renderERB(%q) err = nil", src)
+		}
+	}
+}
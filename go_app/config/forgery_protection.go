@@ -0,0 +1,69 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// ForgeryProtection are the CSRF settings of ActionController.
+type ForgeryProtection struct {
+	// Enabled is `allow_forgery_protection`, usually disabled in the test environment.
+	Enabled bool
+	// PerFormTokens accepts the tokens Rails generates for a single form action.
+	PerFormTokens bool
+	// OriginCheck rejects requests with an Origin header of another site.
+	OriginCheck bool
+	// URLSafeTokens encodes the tokens with the url safe base64 alphabet.
+	URLSafeTokens bool
+
+	perFormSet, originCheckSet, urlSafeSet bool
+}
+
+var rubyControllerAssign = regexp.MustCompile(`config\.action_controller\.(\w+)\s*=\s*(.+?)\s*$`)
+
+// ForgeryProtection reads the CSRF settings from the same files as
+// CookieSettings, with the defaults of `config.load_defaults`.
+func (r *Rails) ForgeryProtection() (*ForgeryProtection, error) {
+	files, err := r.configFiles()
+	if err != nil {
+		return nil, err
+	}
+	fp := &ForgeryProtection{Enabled: true}
+	var loadDefaults float64
+	for _, name := range files {
+		err := scanRuby(name, func(line string) {
+			if m := rubyLoadDefaults.FindStringSubmatch(line); m != nil {
+				loadDefaults, _ = strconv.ParseFloat(m[1], 64)
+				return
+			}
+			m := rubyControllerAssign.FindStringSubmatch(line)
+			if m == nil {
+				return
+			}
+			val := rubyValue(m[2]) == "true"
+			switch m[1] {
+			case "allow_forgery_protection":
+				fp.Enabled = val
+			case "per_form_csrf_tokens":
+				fp.PerFormTokens, fp.perFormSet = val, true
+			case "forgery_protection_origin_check":
+				fp.OriginCheck, fp.originCheckSet = val, true
+			case "urlsafe_csrf_tokens":
+				fp.URLSafeTokens, fp.urlSafeSet = val, true
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !fp.perFormSet {
+		fp.PerFormTokens = loadDefaults >= 5.0
+	}
+	if !fp.originCheckSet {
+		fp.OriginCheck = loadDefaults >= 5.0
+	}
+	if !fp.urlSafeSet {
+		fp.URLSafeTokens = loadDefaults >= 6.1
+	}
+	return fp, nil
+}
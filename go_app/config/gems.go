@@ -0,0 +1,31 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+)
+
+// a locked gem in the specs of Gemfile.lock, e.g. `    go-on-rails (0.3.1)`
+var lockedGem = regexp.MustCompile(`^    ([\w.-]+) \(([^)]+)\)$`)
+
+// GemVersion returns the version of a gem in the Gemfile.lock of the Rails app,
+// "" if the gem isn't locked.
+func (r *Rails) GemVersion(name string) (string, error) {
+	f, err := os.Open(r.Path("Gemfile.lock"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if m := lockedGem.FindStringSubmatch(scanner.Text()); m != nil && m[1] == name {
+			return m[2], nil
+		}
+	}
+	return "", scanner.Err()
+}
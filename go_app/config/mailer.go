@@ -0,0 +1,64 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	rubyDefaultURLOptions = regexp.MustCompile(`config\.action_mailer\.default_url_options\s*=\s*\{(.*)\}`)
+	rubyHashPair          = regexp.MustCompile(`:?(\w+)(?::|\s*=>)\s*([^,]+)`)
+)
+
+// MailerURL returns the base URL of the links of the Rails emails, built from
+// `config.action_mailer.default_url_options`, e.g. "https://example.com:3000".
+// It's "" when the host isn't set.
+func (r *Rails) MailerURL() (string, error) {
+	files, err := r.configFiles()
+	if err != nil {
+		return "", err
+	}
+	var host, port, protocol string
+	for _, name := range files {
+		err := scanRuby(name, func(line string) {
+			m := rubyDefaultURLOptions.FindStringSubmatch(line)
+			if m == nil {
+				return
+			}
+			host, port, protocol = "", "", ""
+			for _, pair := range rubyHashPair.FindAllStringSubmatch(m[1], -1) {
+				val := r.rubyEnvValue(pair[2])
+				switch pair[1] {
+				case "host":
+					host = val
+				case "port":
+					port = val
+				case "protocol":
+					protocol = strings.TrimSuffix(val, "://")
+				}
+			}
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	if host == "" {
+		return "", nil
+	}
+	// the host may hold the protocol and the port too, like in url_for
+	if !strings.Contains(host, "://") {
+		if protocol == "" {
+			protocol = "http"
+		}
+		host = protocol + "://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+	if port != "" && u.Port() == "" {
+		u.Host += ":" + port
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
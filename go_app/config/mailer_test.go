@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMailerURL(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"", ""},
+		{"config.action_mailer.default_url_options = { host: 'example.com' }", "http://example.com"},
+		{"config.action_mailer.default_url_options = { host: 'localhost', port: 3000 }", "http://localhost:3000"},
+		{"config.action_mailer.default_url_options = { :host => \"example.com\", :protocol => 'https' }", "https://example.com"},
+		{"config.action_mailer.default_url_options = { host: 'https://example.com/' }", "https://example.com"},
+	}
+	for _, tt := range tests {
+		rails := testRails(t, "production", map[string]string{
+			"config/environments/production.rb": "Rails.application.configure do\n  " + tt.line + "\nend\n",
+		})
+		got, err := rails.MailerURL()
+		os.RemoveAll(rails.Root)
+		if err != nil || got != tt.want {
+			t.Errorf("MailerURL of %q = %q, %v, want %q", tt.line, got, err, tt.want)
+		}
+	}
+}
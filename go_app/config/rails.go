@@ -0,0 +1,107 @@
+// Package config reads the settings of the Rails app the Go app is integrated in,
+// so that both always agree without duplicating secrets in the Go code.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rails locates the configuration of a Rails app for an environment.
+type Rails struct {
+	// Root is the root directory of the Rails app, the one that contains `config/`.
+	Root string
+	// Env is the Rails environment, e.g. "development" or "production".
+	Env string
+}
+
+// NewRails returns the Rails app in the root directory,
+// the environment is taken from RAILS_ENV and defaults to "development".
+func NewRails(root string) *Rails {
+	return &Rails{Root: root, Env: railsEnv()}
+}
+
+func railsEnv() string {
+	env := os.Getenv("RAILS_ENV")
+	if env == "" {
+		env = os.Getenv("RACK_ENV")
+	}
+	if env == "" {
+		env = "development"
+	}
+	return env
+}
+
+// Path returns the path of a file relative to the Rails root.
+func (r *Rails) Path(elem ...string) string {
+	return filepath.Join(append([]string{r.Root}, elem...)...)
+}
+
+// readYAML reads a YAML file of the Rails app after rendering its ERB tags.
+// It returns nil without error when the file doesn't exist.
+func (r *Rails) readYAML(name string) (map[string]interface{}, error) {
+	src, err := ioutil.ReadFile(r.Path(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	src, err = renderERB(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	m, err := parseYAML(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return m, nil
+}
+
+func parseYAML(src []byte) (map[string]interface{}, error) {
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(src, &raw); err != nil {
+		return nil, err
+	}
+	return stringMap(raw), nil
+}
+
+// stringMap converts the maps decoded by yaml.v2 to map[string]interface{}, recursively.
+func stringMap(m map[interface{}]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[fmt.Sprint(k)] = normalizeYAML(v)
+	}
+	return res
+}
+
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		return stringMap(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeYAML(v[i])
+		}
+	}
+	return v
+}
+
+// section returns the nested map under key, or nil.
+func section(m map[string]interface{}, key string) map[string]interface{} {
+	s, _ := m[key].(map[string]interface{})
+	return s
+}
+
+// stringValue returns a scalar value as a string, or "" if it's missing.
+func stringValue(m map[string]interface{}, key string) string {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
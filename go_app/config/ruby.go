@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Ruby config files aren't evaluated, the settings are picked out of them
+// line by line with the helpers below, which understand the simple literals
+// Rails and Devise settings are usually written with.
+
+// scanRuby calls fn with each line of a Ruby file that isn't a comment.
+// A missing file is ignored.
+func scanRuby(name string, fn func(line string)) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fn(line)
+	}
+	return scanner.Err()
+}
+
+// rubyValue turns a simple Ruby literal into a string:
+// `:json` and `"json"` both become "json", constants and booleans are kept as is.
+func rubyValue(s string) string {
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	s = strings.TrimPrefix(s, ":")
+	return strings.Trim(s, `"'`)
+}
+
+var rubyEnvTernary = regexp.MustCompile(`^Rails\.env\.(\w+)\?\s*\?\s*(.+?)\s*:\s*(.+)$`)
+
+// rubyEnvValue is rubyValue that also evaluates a ternary on the
+// environment, e.g. `Rails.env.test? ? 1 : 11`.
+func (r *Rails) rubyEnvValue(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if m := rubyEnvTernary.FindStringSubmatch(s); m != nil {
+		if m[1] == r.Env {
+			return rubyValue(m[2])
+		}
+		return rubyValue(m[3])
+	}
+	return rubyValue(s)
+}
+
+var rubyDurationExpr = regexp.MustCompile(`^(\d+(?:\.\d+)?)\.(second|minute|hour|day|week|month|year)s?$`)
+
+var rubyDurationUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	// ActiveSupport's lengths of a month and a year
+	"month": 2629746 * time.Second,
+	"year":  31556952 * time.Second,
+}
+
+// rubyDuration parses an ActiveSupport duration like `6.hours` or `2.weeks`,
+// a plain number is a number of seconds.
+func rubyDuration(s string) (time.Duration, bool) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(n) * time.Second, true
+	}
+	m := rubyDurationExpr.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(n * float64(rubyDurationUnits[m[2]])), true
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Secrets returns the secrets of config/secrets.yml for the environment,
+// merged over the `shared` secrets, as Rails.application.secrets does.
+func (r *Rails) Secrets() (map[string]interface{}, error) {
+	all, err := r.readYAML("config/secrets.yml")
+	if err != nil {
+		return nil, err
+	}
+	secrets := map[string]interface{}{}
+	for _, name := range []string{"shared", r.Env} {
+		for k, v := range section(all, name) {
+			secrets[k] = v
+		}
+	}
+	return secrets, nil
+}
+
+// SecretKeyBase returns the secret_key_base Rails derives the cookie keys from.
+// Like Rails in production, a non-empty SECRET_KEY_BASE environment variable
+// takes precedence, then the encrypted credentials and finally
+// config/secrets.yml. In development and test Rails reads config/secrets.yml
+// first, the other ones are only a fallback then.
+func (r *Rails) SecretKeyBase() (string, error) {
+	secrets, err := r.Secrets()
+	if err != nil {
+		return "", err
+	}
+	fromSecrets := stringValue(secrets, "secret_key_base")
+	if fromSecrets != "" && (r.Env == "development" || r.Env == "test") {
+		return fromSecrets, nil
+	}
+	if s := os.Getenv("SECRET_KEY_BASE"); s != "" {
+		return s, nil
+	}
+	creds, err := r.Credentials()
+	if err != nil && err != ErrNoMasterKey {
+		return "", err
+	}
+	if s := stringValue(creds, "secret_key_base"); s != "" {
+		return s, nil
+	}
+	if fromSecrets != "" {
+		return fromSecrets, nil
+	}
+	if err == ErrNoMasterKey {
+		return "", errors.New("config: no secret_key_base in SECRET_KEY_BASE or config/secrets.yml, and the credentials can't be read without RAILS_MASTER_KEY or config/master.key")
+	}
+	return "", fmt.Errorf("config: no secret_key_base found for the %s environment", r.Env)
+}
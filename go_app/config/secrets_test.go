@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSecretKeyBase(t *testing.T) {
+	defer os.Unsetenv("SECRET_KEY_BASE")
+	os.Unsetenv("RAILS_MASTER_KEY")
+	secrets := `
+shared:
+  api_key: a1B2c3D4e5F6
+development:
+  secret_key_base: development-secret
+production:
+  secret_key_base: <%= ENV["PRODUCTION_SECRET"] || "secrets-secret" %>
+`
+	credentials := map[string]string{
+		"config/credentials.yml.enc": testCredentials,
+		"config/master.key":          testMasterKey,
+	}
+	tests := []struct {
+		name        string
+		env         string
+		envSecret   string
+		credentials bool
+		want        string
+	}{
+		{"production env first", "production", "env-secret", true, "env-secret"},
+		{"production credentials", "production", "", true, testCredentialsSecret},
+		{"production secrets.yml", "production", "", false, "secrets-secret"},
+		{"development secrets.yml first", "development", "env-secret", true, "development-secret"},
+		{"test fallback to env", "test", "env-secret", true, "env-secret"},
+		{"test fallback to credentials", "test", "", true, testCredentialsSecret},
+	}
+	for _, tt := range tests {
+		files := map[string]string{"config/secrets.yml": secrets}
+		if tt.credentials {
+			for name, src := range credentials {
+				files[name] = src
+			}
+		}
+		rails := testRails(t, tt.env, files)
+		os.Setenv("SECRET_KEY_BASE", tt.envSecret)
+		got, err := rails.SecretKeyBase()
+		os.RemoveAll(rails.Root)
+		if err != nil {
+			t.Errorf("%s: SecretKeyBase err: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: SecretKeyBase = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSecretKeyBaseMissing(t *testing.T) {
+	os.Unsetenv("SECRET_KEY_BASE")
+	os.Unsetenv("RAILS_MASTER_KEY")
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"nothing", nil},
+		{"no master key", map[string]string{"config/credentials.yml.enc": testCredentials}},
+		{"wrong master key", map[string]string{"config/credentials.yml.enc": testCredentials, "config/master.key": testStagingKey}},
+		{"empty secrets.yml", map[string]string{"config/secrets.yml": "production:\n  secret_key_base: <%= ENV[\"SECRET_KEY_BASE\"] %>\n"}},
+	}
+	for _, tt := range tests {
+		rails := testRails(t, "production", tt.files)
+		if s, err := rails.SecretKeyBase(); err == nil {
+			t.Errorf("%s: SecretKeyBase = %q, want an error", tt.name, s)
+		}
+		os.RemoveAll(rails.Root)
+	}
+}
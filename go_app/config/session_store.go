@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionStore is the `config.session_store` of the Rails app.
+type SessionStore struct {
+	// Store is the kind of store, e.g. "cookie_store" or "active_record_store".
+	Store string
+	// Key is the name of the session cookie, `_<app name>_session` by default.
+	Key    string
+	Path   string
+	Domain string
+	// AllDomains is `domain: :all`, the cookie is shared by the subdomains of the request host.
+	AllDomains bool
+	Secure     bool
+	HTTPOnly   bool
+	// SameSite is "lax", "strict", "none" or "" to leave it out.
+	SameSite    string
+	ExpireAfter time.Duration
+
+	// Serializer, RedisURL and KeyPrefix are the options of the server side stores.
+	Serializer string
+	RedisURL   string
+	KeyPrefix  string
+
+	sameSiteSet bool
+}
+
+var (
+	rubyAppModule    = regexp.MustCompile(`^module\s+([\w:]+)`)
+	rubyAppClass     = regexp.MustCompile(`^class\s+([\w:]+)\s*<\s*Rails::Application`)
+	rubySessionStore = regexp.MustCompile(`config\.session_store\s*\(?\s*:(\w+)\s*,?\s*(.*?)\)?\s*$`)
+	rubyHashOption   = regexp.MustCompile(`(\w+):\s*("[^"]*"|'[^']*'|[^,{}]+)`)
+	underscoreUpper  = regexp.MustCompile(`([A-Z\d]+)([A-Z][a-z])`)
+	underscoreLower  = regexp.MustCompile(`([a-z\d])([A-Z])`)
+)
+
+// AppName returns the name of the Rails app, the underscored module of its
+// Application class in config/application.rb, e.g. "example_read_rails_session".
+func (r *Rails) AppName() (string, error) {
+	var modules []string
+	var class string
+	err := scanRuby(r.Path("config", "application.rb"), func(line string) {
+		if class != "" {
+			return
+		}
+		if m := rubyAppModule.FindStringSubmatch(line); m != nil {
+			modules = append(modules, m[1])
+		} else if m := rubyAppClass.FindStringSubmatch(line); m != nil {
+			class = m[1]
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if class == "" {
+		return "", errors.New("config/application.rb: no Rails::Application class")
+	}
+	// Rails' railtie_name without the "_application" suffix
+	name := underscore(strings.Join(append(modules, class), "::"))
+	return strings.TrimSuffix(strings.Replace(name, "/", "_", -1), "_application"), nil
+}
+
+// underscore is ActiveSupport's String#underscore, without the acronyms.
+func underscore(s string) string {
+	s = strings.Replace(s, "::", "/", -1)
+	s = underscoreUpper.ReplaceAllString(s, "${1}_${2}")
+	s = underscoreLower.ReplaceAllString(s, "${1}_${2}")
+	return strings.ToLower(strings.Replace(s, "-", "_", -1))
+}
+
+// SessionStore reads `config.session_store` from the same files as
+// CookieSettings, usually config/initializers/session_store.rb. Without it the
+// sessions are in the cookie named after the app, as in Rails.
+func (r *Rails) SessionStore() (*SessionStore, error) {
+	files, err := r.configFiles()
+	if err != nil {
+		return nil, err
+	}
+	ss := &SessionStore{Store: "cookie_store", Path: "/", HTTPOnly: true}
+	var (
+		loadDefaults       float64
+		sameSiteProtection string
+		protectionSet      bool
+	)
+	for _, name := range files {
+		var stmt string
+		err := scanRuby(name, func(line string) {
+			if m := rubyLoadDefaults.FindStringSubmatch(line); m != nil {
+				loadDefaults, _ = strconv.ParseFloat(m[1], 64)
+				return
+			}
+			if m := rubyConfigAssign.FindStringSubmatch(line); m != nil && m[1] == "cookies_same_site_protection" {
+				sameSiteProtection, protectionSet = rubySameSite(m[2]), true
+				return
+			}
+			if stmt == "" && !rubySessionStore.MatchString(line) {
+				return
+			}
+			stmt += " " + line
+			// the options may be on several lines, up to one that doesn't continue
+			if strings.HasSuffix(line, ",") || strings.HasSuffix(line, "{") || strings.HasSuffix(line, "(") {
+				return
+			}
+			r.parseSessionStore(ss, strings.TrimSpace(stmt))
+			stmt = ""
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ss.sameSiteSet {
+		// the cookies_same_site_protection of the cookies, Lax since 6.1
+		ss.SameSite = sameSiteProtection
+		if !protectionSet && loadDefaults >= 6.1 {
+			ss.SameSite = "lax"
+		}
+	}
+	if ss.Key == "" {
+		app, err := r.AppName()
+		if err != nil {
+			return nil, err
+		}
+		ss.Key = "_" + app + "_session"
+	}
+	return ss, nil
+}
+
+func (r *Rails) parseSessionStore(ss *SessionStore, stmt string) {
+	m := rubySessionStore.FindStringSubmatch(stmt)
+	if m == nil {
+		return
+	}
+	ss.Store = m[1]
+	for _, opt := range rubyHashOption.FindAllStringSubmatch(m[2], -1) {
+		val := strings.TrimSpace(opt[2])
+		switch opt[1] {
+		case "key":
+			ss.Key = rubyValue(val)
+		case "path":
+			ss.Path = rubyValue(val)
+		case "domain":
+			if val == ":all" {
+				ss.AllDomains = true
+			} else {
+				ss.Domain = rubyValue(val)
+			}
+		case "secure":
+			ss.Secure = r.rubyBool(val)
+		case "httponly":
+			ss.HTTPOnly = r.rubyBool(val)
+		case "same_site":
+			// an explicit nil leaves the attribute out
+			ss.SameSite, ss.sameSiteSet = rubySameSite(val), true
+		case "expire_after":
+			ss.ExpireAfter, _ = rubyDuration(rubyValue(val))
+		case "serializer":
+			ss.Serializer = rubyValue(val)
+		case "url":
+			// e.g. ENV["REDIS_URL"]
+			if url, err := evalERB(val); err == nil {
+				ss.RedisURL = url
+			}
+		case "key_prefix":
+			ss.KeyPrefix = rubyValue(val)
+		}
+	}
+}
+
+// rubySameSite returns the SameSite of a Ruby value like `:lax`, "" for nil.
+func rubySameSite(s string) string {
+	v := strings.ToLower(rubyValue(s))
+	if v == "nil" || v == "false" {
+		return ""
+	}
+	return v
+}
+
+var rubyEnvPredicate = regexp.MustCompile(`^Rails\.env\.(\w+)\?$`)
+
+// rubyBool evaluates a boolean literal or a predicate on the environment,
+// e.g. `secure: Rails.env.production?`.
+func (r *Rails) rubyBool(s string) bool {
+	if m := rubyEnvPredicate.FindStringSubmatch(s); m != nil {
+		return m[1] == r.Env
+	}
+	return r.rubyEnvValue(s) == "true"
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRails writes the config files of a Rails app in a temporary directory,
+// remove it with os.RemoveAll(rails.Root).
+func testRails(t *testing.T, env string, files map[string]string) *Rails {
+	root, err := ioutil.TempDir("", "rails")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Rails{Root: root, Env: env}
+}
+
+func TestSessionStoreSameSite(t *testing.T) {
+	tests := []struct {
+		name         string
+		loadDefaults string
+		config       string
+		want         string
+	}{
+		{"unset before 6.1", "6.0", "", ""},
+		{"unset since 6.1", "6.1", "", "lax"},
+		{"unset in 7.0", "7.0", "", "lax"},
+		{"no load_defaults", "", "", ""},
+		{"explicit nil", "7.0", "Rails.application.config.session_store :cookie_store, same_site: nil", ""},
+		{"explicit empty", "7.0", "Rails.application.config.session_store :cookie_store, same_site: ''", ""},
+		{"explicit strict", "6.0", "Rails.application.config.session_store :cookie_store, same_site: :strict", "strict"},
+		{"cookies protection", "7.0", "Rails.application.config.action_dispatch.cookies_same_site_protection = :strict", "strict"},
+		{"cookies protection nil", "7.0", "Rails.application.config.action_dispatch.cookies_same_site_protection = nil", ""},
+	}
+	for _, tt := range tests {
+		app := "module Shop\n  class Application < Rails::Application\n"
+		if tt.loadDefaults != "" {
+			app += "    config.load_defaults " + tt.loadDefaults + "\n"
+		}
+		app += "  end\nend\n"
+		rails := testRails(t, "production", map[string]string{
+			"config/application.rb":                app,
+			"config/initializers/session_store.rb": tt.config + "\n",
+		})
+		ss, err := rails.SessionStore()
+		os.RemoveAll(rails.Root)
+		if err != nil {
+			t.Fatalf("%s: SessionStore err: %v", tt.name, err)
+		}
+		if ss.SameSite != tt.want || ss.Key != "_shop_session" {
+			t.Errorf("%s: SessionStore = %+v, want SameSite %q", tt.name, ss, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"strings"
+
+	"../config"
+	"../mailer"
+	"../session"
+)
+
+// gorVersion is the version of go-on-rails in the Rails app, shown by HomeHandler
+var gorVersion string
+
+// Setup configures the controllers with the settings of the Go app: the Rails
+// app they share the sessions with, then the overrides of the session store,
+// the trusted proxies, the base URL of the emails and the SMTP server of the mailer.
+func Setup(app *config.App) error {
+	if app.Session.CacheSize > 0 {
+		SessionCache = session.NewCache(app.Session.CacheSize, app.Session.CacheTTL)
+	}
+	rails := app.Rails()
+	if err := Configure(rails); err != nil {
+		return err
+	}
+	if app.Session.Store != "" || app.Session.RedisURL != "" {
+		// the key prefix and the serializer of the Rails app are kept
+		opts := railsStoreOptions
+		if app.Session.Store != "" {
+			opts.Kind = app.Session.Store
+		}
+		if app.Session.RedisURL != "" {
+			opts.RedisURL = app.Session.RedisURL
+		}
+		if err := UseSessionStore(opts); err != nil {
+			return err
+		}
+	}
+	if len(app.TrustedProxies) > 0 {
+		if err := SetTrustedProxies(app.TrustedProxies); err != nil {
+			return err
+		}
+	}
+	railsBaseURL = strings.TrimSuffix(app.RailsURL, "/")
+	if railsBaseURL == "" {
+		var err error
+		if railsBaseURL, err = rails.MailerURL(); err != nil {
+			return err
+		}
+	}
+	if app.SMTP.Address != "" {
+		Mailer = mailer.NewSMTPMailer(app.SMTP.Address, app.SMTP.Username, app.SMTP.Password)
+	}
+
+	var err error
+	gorVersion, err = rails.GemVersion("go-on-rails")
+	return err
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"log"
+
+	m "../models"
+	"../session"
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticatable is a model Devise signs in, e.g. *models.User.
+type Authenticatable interface {
+	AuthenticatableSalt() string
+}
+
+// FindAuthenticatable loads the model of a Devise scope by id.
+type FindAuthenticatable func(id int64) (Authenticatable, error)
+
+// Users are the users of the controllers, the default store of the models.
+var Users m.UserRepository = m.Default()
+
+// FindUser loads the models.User of the "user" scope.
+func FindUser(id int64) (Authenticatable, error) {
+	return Users.FindUser(id)
+}
+
+func currentKey(scope string) string {
+	return "rails.current_" + scope
+}
+
+// LoadWardenUser is a middleware that loads the user signed in with Devise for
+// the scope, it must be used after LoadSession. A request without a valid
+// Warden user just has no current user, use RequireScope or RequireUser to
+// reject it. As in Devise, the salt stored in the session must match the
+// one of the user, so sessions started before a password change are ignored.
+func LoadWardenUser(scope string, find FindAuthenticatable) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, err := SessionFrom(c)
+		if err != nil || !sess.Exists() {
+			return
+		}
+		wu, err := sess.WardenUser(scope)
+		if err != nil {
+			if err != session.ErrNoWardenUser {
+				log.Printf("warden %s err: %v", scope, err)
+			}
+			return
+		}
+		user, err := find(wu.ID)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			abortWithError(c, ErrInternal, err)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(user.AuthenticatableSalt()), []byte(wu.Salt)) != 1 {
+			log.Printf("warden %s #%d: authenticatable salt mismatch", scope, wu.ID)
+			return
+		}
+		c.Set(currentKey(scope), user)
+	}
+}
+
+// Current returns the signed in model of the Devise scope, or nil.
+func Current(c *gin.Context, scope string) Authenticatable {
+	user, _ := c.Value(currentKey(scope)).(Authenticatable)
+	return user
+}
+
+// CurrentUser returns the signed in models.User, or nil.
+func CurrentUser(c *gin.Context) *m.User {
+	user, _ := Current(c, "user").(*m.User)
+	return user
+}
+
+// RequireScope is a guard that responds 401 when nobody is signed in for the
+// Devise scope, like Devise's `authenticate_<scope>!`.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Current(c, scope) != nil {
+			return
+		}
+		if _, err := SessionFrom(c); err != nil {
+			abortWithError(c, sessionError(err), err)
+			return
+		}
+		abortWithError(c, ErrNotSignedIn, nil)
+	}
+}
+
+// RequireUser is RequireScope for the "user" scope.
+func RequireUser() gin.HandlerFunc {
+	return RequireScope("user")
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"../config"
+	"github.com/gin-gonic/gin"
+)
+
+// csrfParam is the form field of the CSRF token, Rails' request_forgery_protection_token.
+const csrfParam = "authenticity_token"
+
+// forgeryProtection are the CSRF settings of the Rails app, loaded by Configure.
+var forgeryProtection = &config.ForgeryProtection{Enabled: true, PerFormTokens: true, OriginCheck: true}
+
+// VerifyAuthenticityToken is a middleware that protects the unsafe requests
+// against CSRF like Rails' protect_from_forgery: a POST, PUT, PATCH or DELETE
+// needs the masked token of the session in the X-CSRF-Token header or the
+// authenticity_token parameter, or it's rejected with 422. It must be used
+// after LoadSession, the tokens of the Rails pages (csrf_meta_tags and forms)
+// and the ones of CSRFToken are interchangeable.
+func VerifyAuthenticityToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !forgeryProtection.Enabled {
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			return
+		}
+		if forgeryProtection.OriginCheck {
+			if origin := c.GetHeader("Origin"); origin != "" && origin != baseURL(c) {
+				abortWithError(c, ErrInvalidCSRFToken, nil)
+				return
+			}
+		}
+		sess, err := SessionFrom(c)
+		if err == nil {
+			path, method := c.Request.URL.Path, c.Request.Method
+			for _, token := range []string{authenticityParam(c), c.GetHeader("X-CSRF-Token")} {
+				if sess.ValidCSRFToken(token, path, method, forgeryProtection.PerFormTokens) {
+					return
+				}
+			}
+		}
+		abortWithError(c, ErrInvalidCSRFToken, nil)
+	}
+}
+
+// baseURL is the scheme and host the request was sent to, like Rails' request.base_url.
+func baseURL(c *gin.Context) string {
+	return requestScheme(c.Request, trustedProxies) + "://" + c.Request.Host
+}
+
+// authenticityParam returns the authenticity_token of a form or a JSON body,
+// the body is kept for the handler.
+func authenticityParam(c *gin.Context) string {
+	if c.ContentType() != gin.MIMEJSON {
+		return c.PostForm(csrfParam)
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	var params struct {
+		Token string `json:"authenticity_token"`
+	}
+	json.Unmarshal(body, &params)
+	return params.Token
+}
+
+// CSRFToken returns a masked CSRF token of the session for a page or a form
+// of the Go app, like Rails' form_authenticity_token.
+func CSRFToken(c *gin.Context) (string, error) {
+	sess, err := SessionFrom(c)
+	if err != nil {
+		sess = ResetSession(c)
+	}
+	return sess.MaskedCSRFToken("", "", forgeryProtection.URLSafeTokens)
+}
+
+// CSRFHandler responds with the CSRF token for clients without a Rails page,
+// the same as the csrf-param and csrf-token of csrf_meta_tags.
+func CSRFHandler(c *gin.Context) {
+	token, err := CSRFToken(c)
+	if err != nil {
+		abortWithError(c, ErrInternal, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"param": csrfParam, "token": token}})
+}
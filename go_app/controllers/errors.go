@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	m "../models"
+	"../session"
+	"github.com/gin-gonic/gin"
+)
+
+// APIError is the error the handlers respond with, as JSON:
+// {"error": {"code": "invalid_session", "message": "..."}}
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	ErrMissingSession   = &APIError{http.StatusUnauthorized, "missing_session", "no Rails session cookie"}
+	ErrInvalidSession   = &APIError{http.StatusUnauthorized, "invalid_session", "the Rails session cookie can't be verified"}
+	ErrExpiredSession   = &APIError{http.StatusUnauthorized, "expired_session", "the Rails session cookie has expired"}
+	ErrMalformedSession = &APIError{http.StatusBadRequest, "malformed_session", "the Rails session data can't be parsed"}
+	ErrNotSignedIn      = &APIError{http.StatusUnauthorized, "not_signed_in", "no user is signed in"}
+	ErrBadRequest       = &APIError{http.StatusBadRequest, "bad_request", "the request parameters can't be parsed"}
+	ErrInvalidLogin     = &APIError{http.StatusUnauthorized, "invalid_credentials", "Invalid Email or password."}
+	ErrUserNotFound     = &APIError{http.StatusNotFound, "user_not_found", "the user doesn't exist"}
+	ErrEmailNotFound    = &APIError{http.StatusUnprocessableEntity, "email_not_found", "Email not found"}
+	ErrInvalidToken     = &APIError{http.StatusUnprocessableEntity, "invalid_reset_password_token", m.ErrInvalidResetToken.Error()}
+	ErrExpiredToken     = &APIError{http.StatusUnprocessableEntity, "expired_reset_password_token", m.ErrExpiredResetToken.Error()}
+	ErrPasswordMismatch = &APIError{http.StatusUnprocessableEntity, "password_mismatch", "Password confirmation doesn't match Password"}
+	ErrInvalidCSRFToken = &APIError{http.StatusUnprocessableEntity, "invalid_authenticity_token", "Can't verify CSRF token authenticity."}
+	ErrInternal         = &APIError{http.StatusInternalServerError, "internal_error", "internal server error"}
+)
+
+// abortWithError logs the cause and responds with the API error, the cause isn't exposed.
+func abortWithError(c *gin.Context, apiErr *APIError, cause error) {
+	if cause != nil {
+		log.Printf("%s %s: %v: %v", c.Request.Method, c.Request.URL.Path, apiErr.Code, cause)
+	}
+	c.AbortWithStatusJSON(apiErr.Status, gin.H{"error": apiErr})
+}
+
+// sessionError classifies the errors of reading a session cookie:
+// cookies that fail verification are unauthorized, the ones with a payload
+// that can't be parsed are bad requests.
+func sessionError(err error) *APIError {
+	switch err {
+	case session.ErrInvalidSignature, session.ErrInvalidCookie, session.ErrPurposeMismatch:
+		return ErrInvalidSession
+	case session.ErrExpired:
+		return ErrExpiredSession
+	case errNoSessionMiddleware:
+		return ErrInternal
+	}
+	return ErrMalformedSession
+}
+
+// dbError classifies the errors of a model lookup.
+func dbError(err error) *APIError {
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	return ErrInternal
+}
+
+// requireSession returns the Rails session of the request, or responds with
+// the matching error and returns nil when there is no valid session.
+func requireSession(c *gin.Context) *session.Session {
+	sess, err := SessionFrom(c)
+	if err != nil {
+		abortWithError(c, sessionError(err), err)
+		return nil
+	}
+	if !sess.Exists() {
+		abortWithError(c, ErrMissingSession, nil)
+		return nil
+	}
+	return sess
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	m "../models"
+	"../session"
+	"github.com/gin-gonic/gin"
+)
+
+// draining is set once the server is shutting down
+var draining int32
+
+// Drain makes ReadyHandler report the app as unavailable, so the load
+// balancer stops sending it requests while the server shuts down.
+func Drain() {
+	atomic.StoreInt32(&draining, 1)
+}
+
+// ReadyHandler is the readiness probe of the app, it's unavailable while
+// draining or when the database doesn't answer.
+func ReadyHandler(c *gin.Context) {
+	if atomic.LoadInt32(&draining) == 1 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	if err := m.Default().Ping(c.Request.Context()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "database_unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// Close releases the connections of the session store and the database,
+// once the server doesn't handle requests anymore.
+func Close() error {
+	var err error
+	if st, ok := sessionStore.(*session.ServerStore); ok {
+		// the SQL backend shares the database of the models
+		if closer, ok := st.Backend.(io.Closer); ok {
+			err = closer.Close()
+		}
+	}
+	if e := m.Default().Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"runtime"
 
 	"github.com/gin-gonic/gin"
 	// you can import models
@@ -23,9 +25,8 @@ func HomeHandler(c *gin.Context) {
 		GolangVer    string
 	}
 
-	gorVer := "0.3.1"
-	golangVer := "go version go1.10.2 darwin/amd64"
+	golangVer := fmt.Sprintf("go version %s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH)
 
-	envs := Envs{GoOnRailsVer: gorVer, GolangVer: golangVer}
+	envs := Envs{GoOnRailsVer: gorVersion, GolangVer: golangVer}
 	c.HTML(http.StatusOK, "index.tmpl", envs)
 }
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"../mailer"
+	m "../models"
+	"github.com/gin-gonic/gin"
+)
+
+// Mailer delivers the Devise emails, the messages are only logged by default,
+// replace it with e.g. mailer.NewSMTPMailer to send them.
+var Mailer mailer.Mailer = mailer.LogMailer{}
+
+type passwordParams struct {
+	User struct {
+		Email                string `json:"email"`
+		ResetPasswordToken   string `json:"reset_password_token"`
+		Password             string `json:"password"`
+		PasswordConfirmation string `json:"password_confirmation"`
+	} `json:"user"`
+}
+
+const sendInstructionsMessage = "You will receive an email with instructions on how to reset your password in a few minutes."
+
+// CreatePasswordHandler emails the reset password instructions, the same as
+// Devise's `POST /users/password` with {"user": {"email": ...}}.
+func CreatePasswordHandler(c *gin.Context) {
+	var params passwordParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		abortWithError(c, ErrBadRequest, err)
+		return
+	}
+	user, token, err := Users.RequestPasswordReset(params.User.Email)
+	if err == sql.ErrNoRows {
+		if m.Devise.Paranoid {
+			// the same answer as for a registered email
+			c.JSON(http.StatusOK, gin.H{"message": sendInstructionsMessage})
+			return
+		}
+		abortWithError(c, ErrEmailNotFound, nil)
+		return
+	}
+	if err != nil {
+		abortWithError(c, ErrInternal, err)
+		return
+	}
+	editURL, err := railsURL("/users/password/edit")
+	if err != nil {
+		abortWithError(c, ErrInternal, err)
+		return
+	}
+	msg := mailer.ResetPasswordInstructions(m.Devise.MailerSender, user.Email, editURL, token)
+	if err := Mailer.Deliver(msg); err != nil {
+		abortWithError(c, ErrInternal, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": sendInstructionsMessage})
+}
+
+// UpdatePasswordHandler resets the password with the token of the email, the same as
+// Devise's `PUT /users/password` with {"user": {"reset_password_token": ..., "password": ...,
+// "password_confirmation": ...}}. The user is signed in afterwards unless
+// sign_in_after_reset_password is disabled.
+func UpdatePasswordHandler(c *gin.Context) {
+	var params passwordParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		abortWithError(c, ErrBadRequest, err)
+		return
+	}
+	if params.User.Password != params.User.PasswordConfirmation {
+		abortWithError(c, ErrPasswordMismatch, nil)
+		return
+	}
+	user, err := Users.ResetPassword(params.User.ResetPasswordToken, params.User.Password)
+	if err != nil {
+		abortWithError(c, passwordError(err), err)
+		return
+	}
+
+	if m.Devise.SignInAfterResetPassword {
+		if err := signIn(c, "user", user.Id, user); err != nil {
+			abortWithError(c, ErrInternal, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+// passwordError classifies the errors of resetting a password.
+func passwordError(err error) *APIError {
+	switch err {
+	case m.ErrInvalidResetToken:
+		return ErrInvalidToken
+	case m.ErrExpiredResetToken:
+		return ErrExpiredToken
+	}
+	if perr, ok := err.(m.PasswordError); ok {
+		return &APIError{http.StatusUnprocessableEntity, "invalid_password", perr.Error()}
+	}
+	return ErrInternal
+}
+
+// railsBaseURL is the base URL of the links to the Rails app, set by Setup.
+// It's never taken from the request, a forged Host header would send the
+// reset tokens to another site.
+var railsBaseURL string
+
+// railsURL is the URL of a page of the Rails app in the emails.
+func railsURL(path string) (string, error) {
+	if railsBaseURL == "" {
+		return "", errors.New("no URL of the Rails app for the email links, set rails_url or action_mailer.default_url_options")
+	}
+	return railsBaseURL + path, nil
+}
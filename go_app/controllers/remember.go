@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	m "../models"
+	"../session"
+	"github.com/gin-gonic/gin"
+)
+
+// rememberJar reads and writes Devise's signed remember cookies, it's built by Configure
+var rememberJar *session.SignedJar
+
+// Rememberable is a model with Devise's rememberable module, e.g. *models.User.
+type Rememberable interface {
+	Authenticatable
+	RememberMe(token string, generatedAt time.Time) bool
+}
+
+// LoadRememberedUser is a middleware that signs in the user of the Devise
+// scope with the remember cookie, e.g. `remember_user_token`, when no user is
+// signed in by the session, like Devise's rememberable strategy. It must be
+// used after LoadWardenUser. With storeInSession the user is signed in the
+// session too, so a fresh session cookie is sent as Rails does, otherwise the
+// user is only current for the request. Invalid remember cookies are deleted.
+func LoadRememberedUser(scope string, find FindAuthenticatable, storeInSession bool) gin.HandlerFunc {
+	name := session.RememberCookieName(scope)
+	return func(c *gin.Context) {
+		if Current(c, scope) != nil {
+			return
+		}
+		cookie, err := c.Request.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			return
+		}
+		token, err := decodeRememberCookie(name, cookie.Value)
+		if err != nil {
+			log.Printf("%s err: %v", name, err)
+			forgetRememberCookie(c, scope)
+			return
+		}
+		user, err := find(token.ID)
+		if err == sql.ErrNoRows {
+			forgetRememberCookie(c, scope)
+			return
+		}
+		if err != nil {
+			abortWithError(c, ErrInternal, err)
+			return
+		}
+		r, ok := user.(Rememberable)
+		if !ok || !r.RememberMe(token.Token, token.GeneratedAt) {
+			forgetRememberCookie(c, scope)
+			return
+		}
+
+		if storeInSession {
+			if err := signIn(c, scope, token.ID, user); err != nil {
+				abortWithError(c, ErrInternal, err)
+				return
+			}
+		} else {
+			c.Set(currentKey(scope), user)
+		}
+		if m.Devise.ExtendRememberPeriod {
+			token.GeneratedAt = time.Now()
+			if err := setRememberCookie(c, scope, token); err != nil {
+				log.Printf("%s err: %v", name, err)
+			}
+		}
+	}
+}
+
+func decodeRememberCookie(name, value string) (*session.RememberToken, error) {
+	data, err := rememberJar.Decode(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return session.ParseRememberToken(data)
+}
+
+// setRememberCookie writes the remember cookie of the scope, valid for remember_for.
+func setRememberCookie(c *gin.Context, scope string, token *session.RememberToken) error {
+	name := session.RememberCookieName(scope)
+	data, err := token.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(m.Devise.RememberFor)
+	value, err := rememberJar.Encode(name, data, expires)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(c.Writer, &http.Cookie{Name: name, Value: value, Path: "/", Expires: expires, HttpOnly: true})
+	return nil
+}
+
+// forgetRememberCookie deletes the remember cookie of the scope.
+func forgetRememberCookie(c *gin.Context, scope string) {
+	if _, err := c.Request.Cookie(session.RememberCookieName(scope)); err != nil {
+		return
+	}
+	http.SetCookie(c.Writer, &http.Cookie{Name: session.RememberCookieName(scope), Path: "/", MaxAge: -1})
+}
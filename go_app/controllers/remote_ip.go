@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultTrustedProxies are the proxies Rails trusts by default,
+// ActionDispatch::RemoteIp::TRUSTED_PROXIES: loopback and private networks.
+var defaultTrustedProxies = []string{
+	"127.0.0.0/8", "::1/128", "fc00::/7", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+}
+
+// trustedProxies are the proxies whose X-Forwarded-For header is believed.
+var trustedProxies = mustParseCIDRs(defaultTrustedProxies)
+
+// SetTrustedProxies replaces the trusted proxies with a list of CIDRs or IPs,
+// an empty list restores the Rails defaults.
+func SetTrustedProxies(proxies []string) error {
+	if len(proxies) == 0 {
+		proxies = defaultTrustedProxies
+	}
+	nets, err := parseCIDRs(proxies)
+	if err != nil {
+		return err
+	}
+	trustedProxies = nets
+	return nil
+}
+
+// RemoteIP returns the IP of the client like Rails' request.remote_ip: the
+// X-Forwarded-For addresses are walked from the closest proxy and the first
+// one that isn't a trusted proxy is the client. Without any forwarded
+// address the peer of the connection is the client.
+func RemoteIP(c *gin.Context) string {
+	return remoteIP(c.Request, trustedProxies)
+}
+
+func remoteIP(r *http.Request, trusted []*net.IPNet) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !isTrusted(net.ParseIP(peer), trusted) {
+		return peer
+	}
+	var forwarded []string
+	for _, h := range r.Header["X-Forwarded-For"] {
+		for _, ip := range strings.Split(h, ",") {
+			if ip = strings.TrimSpace(ip); net.ParseIP(ip) != nil {
+				forwarded = append(forwarded, ip)
+			}
+		}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if !isTrusted(net.ParseIP(forwarded[i]), trusted) {
+			return forwarded[i]
+		}
+	}
+	// every address is a proxy, the farthest one is the closest to the client
+	if len(forwarded) > 0 {
+		return forwarded[0]
+	}
+	return peer
+}
+
+// requestScheme returns the scheme the client used, "http" or "https". Like
+// X-Forwarded-For, X-Forwarded-Proto is only believed from a trusted proxy,
+// any client could send it.
+func requestScheme(r *http.Request, trusted []*net.IPNet) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !isTrusted(net.ParseIP(peer), trusted) {
+		return "http"
+	}
+	// the first proxy tells the scheme of the client, like Rack
+	proto := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")[0])
+	if strings.EqualFold(proto, "https") {
+		return "https"
+	}
+	return "http"
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, s := range cidrs {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func mustParseCIDRs(cidrs []string) []*net.IPNet {
+	nets, err := parseCIDRs(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return nets
+}
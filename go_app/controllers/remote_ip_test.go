@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemoteIP(t *testing.T) {
+	trusted := mustParseCIDRs(defaultTrustedProxies)
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded string
+		want      string
+	}{
+		{"no proxy", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"forged by a client", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"behind a proxy", "10.0.0.2:5000", "198.51.100.1", "198.51.100.1"},
+		{"behind proxies", "10.0.0.2:5000", "198.51.100.9, 198.51.100.1, 192.168.1.1", "198.51.100.1"},
+		{"only proxies", "127.0.0.1:5000", "10.0.0.3, 10.0.0.4", "10.0.0.3"},
+		{"proxy without header", "127.0.0.1:5000", "", "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.peer
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := remoteIP(r, trusted); got != tt.want {
+			t.Errorf("%s: remoteIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequestScheme(t *testing.T) {
+	trusted := mustParseCIDRs(defaultTrustedProxies)
+	tests := []struct {
+		name  string
+		peer  string
+		proto string
+		want  string
+	}{
+		{"plain", "203.0.113.7:5000", "", "http"},
+		{"forged by a client", "203.0.113.7:5000", "https", "http"},
+		{"behind a proxy", "10.0.0.2:5000", "https", "https"},
+		{"behind proxies", "10.0.0.2:5000", "https, http", "https"},
+		{"proxy over http", "10.0.0.2:5000", "http", "http"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.peer
+		if tt.proto != "" {
+			r.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		if got := requestScheme(r, trusted); got != tt.want {
+			t.Errorf("%s: requestScheme = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
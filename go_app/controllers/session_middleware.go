@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+
+	"../session"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionKey      = "rails.session"
+	sessionErrorKey = "rails.session.error"
+)
+
+var errNoSessionMiddleware = errors.New("the LoadSession middleware isn't used by the route")
+
+// LoadSession is a middleware that decrypts the Rails session once per request
+// and stores it in the context, handlers get it with SessionFrom. A request
+// without a session cookie gets an empty session. If a handler changes the
+// session, the session cookie is written back before the response.
+func LoadSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, err := sessionStore.Load(c.Request)
+		if err != nil {
+			c.Set(sessionErrorKey, err)
+		} else {
+			c.Set(sessionKey, sess)
+		}
+
+		w := &sessionWriter{ResponseWriter: c.Writer, c: c}
+		c.Writer = w
+		c.Next()
+		// handlers that write no body, e.g. c.Status(204), have no Write call to hook into
+		w.save()
+	}
+}
+
+// SessionFrom returns the Rails session loaded by the LoadSession middleware,
+// or the error of decrypting the session cookie.
+func SessionFrom(c *gin.Context) (*session.Session, error) {
+	if err, ok := c.Value(sessionErrorKey).(error); ok {
+		return nil, err
+	}
+	v, ok := c.Get(sessionKey)
+	if !ok {
+		return nil, errNoSessionMiddleware
+	}
+	return v.(*session.Session), nil
+}
+
+// ResetSession replaces the session of the request with a new empty one,
+// e.g. when the session cookie can't be decrypted.
+func ResetSession(c *gin.Context) *session.Session {
+	sess := session.New(nil)
+	sess.Clear()
+	c.Set(sessionErrorKey, nil)
+	c.Set(sessionKey, sess)
+	return sess
+}
+
+// Flash returns the Rails flash of the request, a session that can't be read
+// is replaced by a new one. Messages set on it are shown by the next request,
+// e.g. on the Rails page a Go handler redirects to.
+func Flash(c *gin.Context) *session.Flash {
+	sess, err := SessionFrom(c)
+	if err != nil {
+		sess = ResetSession(c)
+	}
+	return sess.Flash()
+}
+
+// sessionWriter writes the changed session cookie right before the response
+// headers are sent, as they can't be changed afterwards.
+type sessionWriter struct {
+	gin.ResponseWriter
+	c     *gin.Context
+	saved bool
+}
+
+func (w *sessionWriter) save() {
+	if w.saved || w.ResponseWriter.Written() {
+		return
+	}
+	w.saved = true
+	sess, err := SessionFrom(w.c)
+	if err != nil {
+		return
+	}
+	sess.CommitFlash()
+	if !sess.Changed() {
+		return
+	}
+	if err := sessionStore.Save(w.ResponseWriter, w.c.Request, sess); err != nil {
+		log.Printf("save session err: %v", err)
+	}
+}
+
+func (w *sessionWriter) WriteHeaderNow() {
+	w.save()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *sessionWriter) Write(data []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *sessionWriter) WriteString(s string) (int, error) {
+	w.save()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *sessionWriter) Flush() {
+	w.save()
+	w.ResponseWriter.Flush()
+}
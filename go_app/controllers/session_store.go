@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+
+	m "../models"
+	"../session"
+)
+
+// StoreOptions select the session store of the Rails app, the first argument
+// of its `config.session_store`.
+type StoreOptions struct {
+	// Kind is "cookie_store", "active_record_store" or "redis_session_store".
+	Kind string
+	// Serializer of the server side stores, by default the one of the gem:
+	// marshal for activerecord-session_store and json for redis-session-store.
+	Serializer session.Serializer
+	// RedisURL and KeyPrefix are the `redis: {url:, key_prefix:}` of redis-session-store.
+	RedisURL  string
+	KeyPrefix string
+}
+
+// railsStoreOptions are the options of the config.session_store of the Rails app, set by Configure.
+var railsStoreOptions StoreOptions
+
+// UseSessionStore replaces the cookie store with the server side store of the
+// Rails app, Configure calls it with the config.session_store of the app. The
+// session id cookie has the same name and attributes as the session cookie.
+//
+// `:cache_store` isn't supported, the sessions are ActiveSupport::Cache::Entry
+// objects the Marshal decoder can't read.
+func UseSessionStore(opts StoreOptions) error {
+	store := &session.ServerStore{Serializer: opts.Serializer, Options: cookieStore.Options}
+	switch opts.Kind {
+	case "", "cookie_store":
+		sessionStore = cookieStore
+		return nil
+	case "active_record_store":
+		store.Backend = session.NewSQLBackend(m.Default().DB())
+		if store.Serializer == "" {
+			store.Serializer = session.MarshalSerializer
+		}
+	case "redis_session_store":
+		if opts.RedisURL == "" {
+			opts.RedisURL = "redis://localhost:6379/0"
+		}
+		backend, err := session.NewRedisBackend(opts.RedisURL, opts.KeyPrefix)
+		if err != nil {
+			return err
+		}
+		store.Backend = backend
+		if store.Serializer == "" {
+			store.Serializer = session.JSONSerializer
+		}
+	default:
+		return fmt.Errorf("unsupported session store %q", opts.Kind)
+	}
+	sessionStore = store
+	return nil
+}
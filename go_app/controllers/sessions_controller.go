@@ -1,60 +1,211 @@
 package controllers
 
 import (
-	"encoding/json"
+	"database/sql"
 	"log"
 	"net/http"
+	"time"
 
+	"../config"
 	m "../models"
-	sj "github.com/bitly/go-simplejson"
+	"../session"
 	"github.com/gin-gonic/gin"
-	"github.com/goonr/gorails/session"
 )
 
-const (
-	// here use `development` env secret_key_base from Rails' config/secret.yml
-	secretKeyBase = "36bf5d0c4782351d14190f9188037459950778b650bc9efe64902c76c3c1bab1759d1f0e4e1e424e1f4e7a3c9da9687f61ef1bc5280460b4305440a101def62d"
-	salt          = "encrypted cookie"        // default value for Rails 4 app
-	signSalt      = "signed encrypted cookie" // default value for Rails 4 app
+var (
+	// cookieStore reads and writes the session cookies, it's built by Configure
+	cookieStore *session.CookieStore
+	// sessionStore is the store of the sessions, the cookieStore unless UseSessionStore changes it
+	sessionStore session.SessionStore
+
+	// after rotating the secret_key_base in Rails append the old key here
+	// so the sessions it wrote keep working, e.g. the old key of a Rails app upgraded to SHA256:
+	// {SecretKeyBase: "<old secret_key_base>", Digest: session.SHA1},
+	rotatedKeys = []session.Key{}
+
+	// SessionCache, if set before Configure, keeps the data of the decrypted session
+	// cookies so a client's next requests skip the decryption, see session.NewCache
+	SessionCache *session.Cache
 )
 
-func ReadHandler(c *gin.Context) {
-	// the session's key format: _<your rails app name>_session
-	// in this example our app name is "example_read_rails_session", so the key is "_example_read_rails_session_session"
-	sess, err := c.Request.Cookie("_example_read_rails_session_session")
+// Configure loads the secret_key_base, the cookie settings and the session store from
+// the Rails app, so the Go app always reads and writes the sessions like Rails.
+// The session cookie is named `_<app name>_session` unless config.session_store sets its key.
+func Configure(rails *config.Rails) error {
+	secretKeyBase, err := rails.SecretKeyBase()
+	if err != nil {
+		return err
+	}
+	cookies, err := rails.CookieSettings()
 	if err != nil {
-		log.Fatalf("read cookie err: %v", err)
+		return err
 	}
-	sessData, err := getRailsSessionData(sess.Value)
+	store, err := rails.SessionStore()
 	if err != nil {
-		log.Fatalf("deserialize err: %v", err)
+		return err
 	}
-	var jsonData map[string]interface{}
-	err = json.Unmarshal(sessData, &jsonData)
+	devise, err := rails.Devise()
 	if err != nil {
-		log.Fatalf("json unmarshal err: %v", err)
+		return err
+	}
+	if devise.SecretKey == "" {
+		devise.SecretKey = secretKeyBase
+	}
+	devise.KeyDigest = cookies.Digest
+	m.Devise = *devise
+	if forgeryProtection, err = rails.ForgeryProtection(); err != nil {
+		return err
+	}
+	keys := append(cookies.Keys(secretKeyBase), rotatedKeys...)
+	keyring := session.NewKeyring(keys[0], keys[1:]...)
+	keyring.Cache = SessionCache
+	keyring.OnDeprecated = func(match session.Match) {
+		log.Printf("session decrypted with deprecated key #%d (%s)", match.Index, match.Cipher)
+	}
+	rememberJar = &session.SignedJar{
+		Keyring:    keyring,
+		Serializer: cookies.Serializer,
+		Metadata:   cookies.Metadata,
+	}
+	cookieStore = &session.CookieStore{
+		Keyring:    keyring,
+		Serializer: cookies.Serializer,
+		Metadata:   cookies.Metadata,
+		Options: session.CookieOptions{
+			Name:        store.Key,
+			Path:        store.Path,
+			Domain:      store.Domain,
+			AllDomains:  store.AllDomains,
+			Secure:      store.Secure,
+			HTTPOnly:    store.HTTPOnly,
+			SameSite:    sameSite(store.SameSite),
+			ExpireAfter: store.ExpireAfter,
+		},
 	}
+	railsStoreOptions = StoreOptions{
+		Kind:       store.Store,
+		Serializer: session.Serializer(store.Serializer),
+		RedisURL:   store.RedisURL,
+		KeyPrefix:  store.KeyPrefix,
+	}
+	return UseSessionStore(railsStoreOptions)
+}
 
-	c.JSON(http.StatusOK, gin.H{"data": jsonData})
+// sameSite returns the SameSite attribute of a Rails `same_site` option,
+// without one the cookie has no SameSite attribute.
+func sameSite(s string) http.SameSite {
+	switch s {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteDefaultMode
 }
 
-func UserHandler(c *gin.Context) {
-	sess, err := c.Request.Cookie("_example_read_rails_session_session")
-	if err != nil {
-		log.Fatalf("read cookie err: %v", err)
+// ReadHandler shows the Rails session, the keys of Rails and Devise typed and all the values.
+func ReadHandler(c *gin.Context) {
+	sess := requireSession(c)
+	if sess == nil {
+		return
 	}
-	sessData, err := getRailsSessionData(sess.Value)
+	rails, err := sess.Rails()
 	if err != nil {
-		log.Fatalf("deserialize err: %v", err)
+		abortWithError(c, ErrMalformedSession, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rails})
+}
+
+// FlashHandler shows the flash messages set for this request, e.g. by a Rails
+// action redirecting here, they're consumed as when a Rails page renders them.
+func FlashHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": Flash(c)})
+}
+
+// UserHandler shows the user signed in with Devise, it's guarded by RequireUser.
+func UserHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": CurrentUser(c)})
+}
+
+type signInParams struct {
+	User struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	} `json:"user"`
+}
+
+// SignInHandler signs a user in with an email and a password, the same as
+// Devise's `POST /users/sign_in` with {"user": {"email": ..., "password": ...}}.
+// The Warden user is stored in the session, so Rails recognises the user too.
+func SignInHandler(c *gin.Context) {
+	var params signInParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		abortWithError(c, ErrBadRequest, err)
+		return
+	}
+	user, err := Users.FindUserForAuthentication(params.User.Email)
+	if err != nil && err != sql.ErrNoRows {
+		abortWithError(c, ErrInternal, err)
+		return
+	}
+	if user == nil || !user.ValidPassword(params.User.Password) {
+		abortWithError(c, ErrInvalidLogin, nil)
+		return
+	}
+	if err := signIn(c, "user", user.Id, user); err != nil {
+		abortWithError(c, ErrInternal, err)
+		return
 	}
-	jsn, _ := sj.NewJson(sessData)
-	uid, _ := jsn.Get("warden.user.user.key").GetIndex(0).GetIndex(0).Int64()
 
-	user, _ := m.FindUser(uid)
 	c.JSON(http.StatusOK, gin.H{"data": user})
 }
 
-func getRailsSessionData(sessionCookie string) (decryptedCookieData []byte, err error) {
-	decryptedCookieData, err = session.DecryptSignedCookie(sessionCookie, secretKeyBase, salt, signSalt)
-	return
+// trackable is a model with Devise's trackable module, e.g. *models.User.
+type trackable interface {
+	TrackSignIn(ip string, at time.Time) error
+}
+
+// signIn tracks the sign-in of the user and stores it in the session as the
+// Warden user of the scope, like Devise's sign_in.
+func signIn(c *gin.Context, scope string, id int64, user Authenticatable) error {
+	if t, ok := user.(trackable); ok {
+		if err := t.TrackSignIn(RemoteIP(c), time.Now()); err != nil {
+			return err
+		}
+	}
+	sess, err := SessionFrom(c)
+	if err != nil {
+		sess = ResetSession(c)
+	}
+	// a new session id against session fixation and a new CSRF token, as Devise does
+	sess.Renew()
+	sess.Delete("_csrf_token")
+	sess.SetWardenUser(session.WardenUser{Scope: scope, ID: id, Salt: user.AuthenticatableSalt()})
+	c.Set(currentKey(scope), user)
+	return nil
+}
+
+// SignOutHandler signs the user out by resetting the Rails session and
+// forgetting the remember cookie, the same as Devise's `DELETE /users/sign_out`.
+func SignOutHandler(c *gin.Context) {
+	if user := CurrentUser(c); user != nil {
+		if err := Users.ForgetMe(user); err != nil {
+			abortWithError(c, ErrInternal, err)
+			return
+		}
+	}
+	forgetRememberCookie(c, "user")
+	sess, err := SessionFrom(c)
+	if err != nil {
+		// a session that can't be read is replaced by a new one
+		log.Printf("read session err: %v", err)
+		ResetSession(c)
+	} else {
+		sess.Clear()
+	}
+	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ResetPasswordInstructions is the email of Devise's
+// reset_password_instructions, editURL is the page of the Rails app the
+// token is appended to, e.g. "http://localhost:3000/users/password/edit".
+func ResetPasswordInstructions(from, to, editURL, token string) *Message {
+	link := editURL + "?reset_password_token=" + url.QueryEscape(token)
+	return &Message{
+		From:    from,
+		To:      []string{to},
+		Subject: "Reset password instructions",
+		Body: fmt.Sprintf(`Hello %s!
+
+Someone has requested a link to change your password. You can do this through the link below.
+
+%s
+
+If you didn't request this, please ignore this email.
+Your password won't change until you access the link above and create a new one.
+`, to, link),
+		Secrets: []string{url.QueryEscape(token)},
+	}
+}
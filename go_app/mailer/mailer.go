@@ -0,0 +1,79 @@
+// Package mailer sends the emails of the Go app, like the Devise mails of
+// the Rails app. Use LogMailer to stub the delivery locally.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+	// Secrets are the parts of the body LogMailer doesn't print, e.g. the tokens of the links.
+	Secrets []string
+}
+
+// Mailer delivers the messages.
+type Mailer interface {
+	Deliver(msg *Message) error
+}
+
+// LogMailer doesn't send the messages, it logs them, the same as Rails'
+// `delivery_method = :test` but visible in the output of the server. The
+// secrets of the messages are logged as [FILTERED], like Rails' filter_parameters.
+type LogMailer struct{}
+
+func (LogMailer) Deliver(msg *Message) error {
+	body := msg.Body
+	for _, secret := range msg.Secrets {
+		if secret != "" {
+			body = strings.Replace(body, secret, "[FILTERED]", -1)
+		}
+	}
+	log.Printf("mail from %s to %s: %s\n%s", msg.From, strings.Join(msg.To, ", "), msg.Subject, body)
+	return nil
+}
+
+// SMTPMailer sends the messages to an SMTP server like Rails'
+// `delivery_method = :smtp`, Auth is optional.
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+}
+
+// NewSMTPMailer returns an SMTPMailer for the server at addr (host:port),
+// with PLAIN authentication when a username is given.
+func NewSMTPMailer(addr, username, password string) *SMTPMailer {
+	m := &SMTPMailer{Addr: addr}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Deliver(msg *Message) error {
+	return smtp.SendMail(m.Addr, m.Auth, msg.From, msg.To, msg.Bytes())
+}
+
+// Bytes formats the message as RFC 5322.
+func (msg *Message) Bytes() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.Replace(msg.Body, "\n", "\r\n", -1))
+	return b.Bytes()
+}
@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogMailerFiltersSecrets(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	token := "x+y/z"
+	msg := ResetPasswordInstructions("please-change-me@example.com", "user@example.com", "https://example.com/users/password/edit", token)
+	if err := (LogMailer{}).Deliver(msg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "x%2By%2Fz") || !strings.Contains(out.String(), "reset_password_token=[FILTERED]") {
+		t.Errorf("LogMailer logged:\n%s", out.String())
+	}
+	if !strings.Contains(msg.Body, "https://example.com/users/password/edit?reset_password_token=x%2By%2Fz") {
+		t.Errorf("Body = %s", msg.Body)
+	}
+}
@@ -2,22 +2,97 @@ package main
 
 import (
 	"flag"
+	"log"
+	"net/http"
 
+	"./config"
 	c "./controllers"
+	m "./models"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// The settings are read from config/app.yml for RAILS_ENV, then from the environment
+	// variables (see config.AppEnvVars) and finally from the flags set here
+	configFile := flag.String("config", "config/app.yml", "Go App Config File")
+	d := config.DefaultApp
 	// The app will run on port 4000 by default, you can custom it with the flag -port
-	servePort := flag.String("port", "4000", "Http Server Port")
+	flag.String("port", d.Port, "Http Server Port")
+	// The secrets and cookie settings are read from the Rails app, by default the parent directory
+	flag.String("rails-root", d.RailsRoot, "Rails App Root Directory")
+	// The links of the Devise emails point to this URL, Rails' action_mailer.default_url_options if empty
+	flag.String("rails-url", "", "Rails App Base URL")
+	// The client IP is taken from X-Forwarded-For only behind these proxies, Rails' defaults if empty
+	flag.String("trusted-proxies", "", "Comma Separated Trusted Proxy CIDRs")
+	// The database is the one of config/database.yml of the Rails app unless a DSN is set
+	flag.String("database-driver", "", "Database Driver: mysql, postgres or sqlite3")
+	flag.String("database-dsn", "", "Database DSN")
+	// The Devise emails are sent to this SMTP server (host:port), they're only logged if empty
+	flag.String("smtp", "", "SMTP Server Address")
+	// The session store is the one of the Rails app's config.session_store unless it's set here
+	flag.String("session-store", "", "Session Store: cookie_store, active_record_store or redis_session_store")
+	flag.String("redis-url", "", "Redis URL of redis_session_store")
+	// The decrypted session cookies are cached when it's not 0, e.g. -session-cache 10000
+	flag.Int("session-cache", d.Session.CacheSize, "Number of Decrypted Session Cookies Cached")
+	flag.Duration("session-cache-ttl", d.Session.CacheTTL, "Time a Decrypted Session Cookie Is Cached")
+	// The server drops slow clients and drains the requests in flight on SIGTERM or SIGINT
+	flag.Duration("read-timeout", d.Server.ReadTimeout, "Http Server Read Timeout")
+	flag.Duration("write-timeout", d.Server.WriteTimeout, "Http Server Write Timeout")
+	flag.Duration("idle-timeout", d.Server.IdleTimeout, "Http Server Idle Connection Timeout")
+	// On shutdown /ready fails for the drain delay before the connections are closed, 0 to skip it
+	flag.Duration("drain-delay", d.Server.DrainDelay, "Time the Readiness Probe Fails Before Shutdown")
+	flag.Duration("shutdown-timeout", d.Server.ShutdownTimeout, "Http Server Shutdown Deadline")
 	flag.Parse()
 
+	app, err := config.LoadApp(*configFile)
+	if err != nil {
+		log.Fatalf("load config err: %v", err)
+	}
+	// only the flags given on the command line override the config
+	if err := app.SetFlags(flag.CommandLine); err != nil {
+		log.Fatal(err)
+	}
+	if err := app.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	// the database is the one of the Rails app unless a DSN is set, it isn't
+	// connected until the first query so the app starts before the database
+	if app.Database.DSN != "" {
+		err = m.Open(app.Database.Driver, app.Database.DSN)
+	} else {
+		var db *m.DatabaseConfig
+		if db, err = m.RailsDatabase(app.Rails()); err == nil {
+			err = m.OpenDatabase(db)
+		}
+	}
+	if err != nil {
+		log.Fatalf("open database err: %v", err)
+	}
+	if err := c.Setup(app); err != nil {
+		log.Fatalf("load rails config err: %v", err)
+	}
+
 	// Here we are instantiating the router
 	r := gin.Default()
 	r.StaticFile("/favicon.ico", "./public/favicon.ico")
-	// Then we bind some route to some handler(controller action)
-	r.GET("/", c.ReadHandler)
-	r.GET("/user", c.UserHandler)
+	// the readiness probe, it fails while the server drains the requests on shutdown
+	r.GET("/ready", c.ReadyHandler)
+	// Then we bind some route to some handler(controller action),
+	// the Rails session and the Devise user are loaded once per request for the routes of this group,
+	// the unsafe requests need the CSRF token of the session like in Rails, and
+	// a user remembered by the `remember_user_token` cookie is signed in the session again
+	s := r.Group("/", c.LoadSession(), c.VerifyAuthenticityToken(), c.LoadWardenUser("user", c.FindUser), c.LoadRememberedUser("user", c.FindUser, true))
+	s.GET("/", c.ReadHandler)
+	s.GET("/csrf", c.CSRFHandler)
+	s.GET("/flash", c.FlashHandler)
+	s.GET("/user", c.RequireUser(), c.UserHandler)
+	s.POST("/users/sign_in", c.SignInHandler)
+	s.DELETE("/users/sign_out", c.SignOutHandler)
+	s.POST("/users/password", c.CreatePasswordHandler)
+	s.PUT("/users/password", c.UpdatePasswordHandler)
 	// Let's start the server
-	r.Run(":" + *servePort)
+	if err := serve(":"+app.Port, r, app.Server); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("server err: %v", err)
+	}
 }
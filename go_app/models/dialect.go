@@ -0,0 +1,227 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	_ "github.com/railstack/go-sqlite3"
+)
+
+// dialect is the SQL the generated queries differ on between the databases.
+type dialect struct {
+	// nullTime selects a timestamp column with NULL as a zero time.Time, SQLite
+	// reads the column as is, see sqliteRows
+	nullTime string
+	// returning is set for the databases without LastInsertId, the INSERTs
+	// return the id instead
+	returning bool
+	// ipText formats an IP column as text, Devise's migrations make them inet
+	// on PostgreSQL, which doesn't compare with strings and prints netmasks
+	ipText string
+}
+
+// dialects by database/sql driver name
+var dialects = map[string]dialect{
+	"mysql":    {nullTime: "COALESCE(%s, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC'))", ipText: "%s"},
+	"postgres": {nullTime: "COALESCE(%s, '0001-01-01 00:00:00'::timestamp)", returning: true, ipText: "split_part(%s::text, '/', 1)"},
+	"sqlite3":  {nullTime: "%s", ipText: "%s"},
+}
+
+// time returns a timestamp column, NULL being read as a zero time.Time.
+func (d dialect) time(col string) string {
+	return fmt.Sprintf(d.nullTime, col)
+}
+
+// ip returns the text of an IP column, for the string and the inet columns.
+func (d dialect) ip(col string) string {
+	return fmt.Sprintf(d.ipText, col)
+}
+
+// nullIP is the value of an IP column, NULL without an IP like in Rails, an
+// inet column doesn't take an empty string.
+func nullIP(ip string) interface{} {
+	if ip == "" {
+		return nil
+	}
+	return ip
+}
+
+// driverName returns the database/sql driver of a Rails adapter, e.g.
+// "postgres" for "postgresql", the names of the drivers are kept as is.
+func driverName(adapter string) string {
+	switch adapter {
+	case "mysql2", "trilogy":
+		return "mysql"
+	case "postgresql", "postgis":
+		return "postgres"
+	}
+	return adapter
+}
+
+// connect opens the database of a driver or a Rails adapter with its dialect,
+// database/sql only connects on the first query.
+func connect(adapter, dsn string) (*sqlx.DB, dialect, error) {
+	name := driverName(adapter)
+	d, ok := dialects[name]
+	if !ok {
+		return nil, dialect{}, fmt.Errorf("unsupported database %q, the drivers are mysql, postgres and sqlite3", adapter)
+	}
+	if name == "sqlite3" {
+		return sqlx.NewDb(openSQLite(dsn), name), d, nil
+	}
+	db, err := sqlx.Open(name, dsn)
+	if err != nil {
+		return nil, dialect{}, err
+	}
+	return db, d, nil
+}
+
+// insertReturningID runs an INSERT with named parameters and returns the id
+// of the new record.
+func insertReturningID(db *sqlx.DB, d dialect, query string, arg interface{}) (int64, error) {
+	if !d.returning {
+		result, err := db.NamedExec(query, arg)
+		if err != nil {
+			return 0, err
+		}
+		return result.LastInsertId()
+	}
+	rows, err := db.NamedQuery(query+" RETURNING id", arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var id int64
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, sql.ErrNoRows
+	}
+	err = rows.Scan(&id)
+	return id, err
+}
+
+// SQLite has no timestamp type, the columns are only declared as datetime and
+// the expressions, e.g. a COALESCE, have no declared type. openSQLite reads the
+// columns declared as a date or a timestamp as time.Time, the NULL ones as the
+// zero time like the COALESCE of the other databases.
+
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func openSQLite(dsn string) *sql.DB {
+	// the driver of the registered "sqlite3", sql.Open doesn't connect
+	db, _ := sql.Open("sqlite3", "")
+	return sql.OpenDB(sqliteConnector{db.Driver(), dsn})
+}
+
+type sqliteConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteConn{conn}, nil
+}
+
+func (c sqliteConnector) Driver() driver.Driver { return c.driver }
+
+// sqliteConn leaves out the optional interfaces of the driver, so the
+// queries go through Prepare and their rows through sqliteRows.
+type sqliteConn struct {
+	driver.Conn
+}
+
+func (c sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteStmt{stmt}, nil
+}
+
+type sqliteStmt struct {
+	driver.Stmt
+}
+
+func (s sqliteStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.Stmt.Query(args)
+	if err != nil {
+		return nil, err
+	}
+	r := sqliteRows{Rows: rows}
+	if typed, ok := rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		for i := range rows.Columns() {
+			if sqliteTimeType(typed.ColumnTypeDatabaseTypeName(i)) {
+				r.timeCols = append(r.timeCols, i)
+			}
+		}
+	}
+	return r, nil
+}
+
+// sqliteTimeType tells if the declared type of a column is a timestamp, e.g.
+// "datetime" or "datetime(6)" of the Rails migrations.
+func sqliteTimeType(decl string) bool {
+	decl = strings.ToUpper(decl)
+	if i := strings.IndexByte(decl, '('); i >= 0 {
+		decl = decl[:i]
+	}
+	switch strings.TrimSpace(decl) {
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+type sqliteRows struct {
+	driver.Rows
+	// timeCols are the indexes of the timestamp columns
+	timeCols []int
+}
+
+func (r sqliteRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for _, i := range r.timeCols {
+		switch v := dest[i].(type) {
+		case nil:
+			dest[i] = time.Time{}
+		case []byte:
+			dest[i] = parseSQLiteTime(string(v))
+		case string:
+			dest[i] = parseSQLiteTime(v)
+		}
+	}
+	return nil
+}
+
+// parseSQLiteTime parses the text of a timestamp, it's left as is unless it
+// has one of the formats of sqliteTimeFormats.
+func parseSQLiteTime(s string) driver.Value {
+	for _, layout := range sqliteTimeFormats {
+		if t, err := time.ParseInLocation(layout, strings.TrimSuffix(s, "Z"), time.UTC); err == nil {
+			return t
+		}
+	}
+	return s
+}
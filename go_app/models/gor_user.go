@@ -33,8 +33,36 @@ type User struct {
 	UpdatedAt           time.Time `json:"updated_at,omitempty" db:"updated_at" valid:"-"`
 }
 
+// userCols is the select list of the User records, the NULL columns are read as zero values.
+func (s *Store) userCols() string {
+	d := s.dialect
+	return "COALESCE(users.reset_password_token, '') AS reset_password_token, " + d.time("users.reset_password_sent_at") + " AS reset_password_sent_at, " + d.time("users.remember_created_at") + " AS remember_created_at, " + d.time("users.current_sign_in_at") + " AS current_sign_in_at, " + d.time("users.last_sign_in_at") + " AS last_sign_in_at, COALESCE(" + d.ip("users.current_sign_in_ip") + ", '') AS current_sign_in_ip, COALESCE(" + d.ip("users.last_sign_in_ip") + ", '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at"
+}
+
+// userArgs are the named parameters of a User, with NULL for the missing IPs.
+type userArgs struct {
+	*User
+	CurrentSignInIp interface{} `db:"current_sign_in_ip"`
+	LastSignInIp    interface{} `db:"last_sign_in_ip"`
+}
+
+func newUserArgs(_user *User) userArgs {
+	return userArgs{_user, nullIP(_user.CurrentSignInIp), nullIP(_user.LastSignInIp)}
+}
+
+// nullUserIPs sets the empty IPs of an attributes map to NULL like newUserArgs.
+func nullUserIPs(am map[string]interface{}) {
+	for _, k := range []string{"current_sign_in_ip", "last_sign_in_ip"} {
+		if ip, ok := am[k].(string); ok {
+			am[k] = nullIP(ip)
+		}
+	}
+}
+
 // DataStruct for the pagination
 type UserPage struct {
+	// Store is the store the pages are read from, the default one if nil
+	Store       *Store
 	WhereString string
 	WhereParams []interface{}
 	Order       map[string]string
@@ -47,6 +75,13 @@ type UserPage struct {
 	orderStr    string
 }
 
+func (_p *UserPage) store() *Store {
+	if _p.Store != nil {
+		return _p.Store
+	}
+	return defaultStore
+}
+
 // Current get the current page of UserPage object for pagination.
 func (_p *UserPage) Current() ([]User, error) {
 	if _, exist := _p.Order["id"]; !exist {
@@ -63,7 +98,7 @@ func (_p *UserPage) Current() ([]User, error) {
 	whereStr := fmt.Sprintf("%s %s %s LIMIT %v", _p.WhereString, idStr, _p.orderStr, _p.PerPage)
 	whereParams := []interface{}{}
 	whereParams = append(append(whereParams, _p.WhereParams...), idParams...)
-	users, err := FindUsersWhere(whereStr, whereParams...)
+	users, err := _p.store().FindUsersWhere(whereStr, whereParams...)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +127,7 @@ func (_p *UserPage) Previous() ([]User, error) {
 	whereStr := fmt.Sprintf("%s %s %s LIMIT %v", _p.WhereString, idStr, _p.orderStr, _p.PerPage)
 	whereParams := []interface{}{}
 	whereParams = append(append(whereParams, _p.WhereParams...), idParams...)
-	users, err := FindUsersWhere(whereStr, whereParams...)
+	users, err := _p.store().FindUsersWhere(whereStr, whereParams...)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +157,7 @@ func (_p *UserPage) Next() ([]User, error) {
 	whereStr := fmt.Sprintf("%s %s %s LIMIT %v", _p.WhereString, idStr, _p.orderStr, _p.PerPage)
 	whereParams := []interface{}{}
 	whereParams = append(append(whereParams, _p.WhereParams...), idParams...)
-	users, err := FindUsersWhere(whereStr, whereParams...)
+	users, err := _p.store().FindUsersWhere(whereStr, whereParams...)
 	if err != nil {
 		return nil, err
 	}
@@ -201,7 +236,7 @@ func (_p *UserPage) buildIdRestrict(direction string) (idStr string, idParams []
 
 // buildPageCount calculate the TotalItems/TotalPages for the UserPage object.
 func (_p *UserPage) buildPageCount() error {
-	count, err := UserCountWhere(_p.WhereString, _p.WhereParams...)
+	count, err := _p.store().UserCountWhere(_p.WhereString, _p.WhereParams...)
 	if err != nil {
 		return err
 	}
@@ -214,12 +249,16 @@ func (_p *UserPage) buildPageCount() error {
 }
 
 // FindUser find a single user by an ID.
-func FindUser(id int64) (*User, error) {
+func (s *Store) FindUser(id int64) (*User, error) {
 	if id == 0 {
 		return nil, errors.New("Invalid ID: it can't be zero")
 	}
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	_user := User{}
-	err := DB.Get(&_user, DB.Rebind(`SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users WHERE users.id = ? LIMIT 1`), id)
+	err = db.Get(&_user, db.Rebind("SELECT "+s.userCols()+` FROM users WHERE users.id = ? LIMIT 1`), id)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -228,9 +267,13 @@ func FindUser(id int64) (*User, error) {
 }
 
 // FirstUser find the first one user by ID ASC order.
-func FirstUser() (*User, error) {
+func (s *Store) FirstUser() (*User, error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	_user := User{}
-	err := DB.Get(&_user, DB.Rebind(`SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users ORDER BY users.id ASC LIMIT 1`))
+	err = db.Get(&_user, db.Rebind("SELECT "+s.userCols()+` FROM users ORDER BY users.id ASC LIMIT 1`))
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -239,10 +282,14 @@ func FirstUser() (*User, error) {
 }
 
 // FirstUsers find the first N users by ID ASC order.
-func FirstUsers(n uint32) ([]User, error) {
+func (s *Store) FirstUsers(n uint32) ([]User, error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	_users := []User{}
-	sql := fmt.Sprintf("SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users ORDER BY users.id ASC LIMIT %v", n)
-	err := DB.Select(&_users, DB.Rebind(sql))
+	sql := fmt.Sprintf("SELECT "+s.userCols()+" FROM users ORDER BY users.id ASC LIMIT %v", n)
+	err = db.Select(&_users, db.Rebind(sql))
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -251,9 +298,13 @@ func FirstUsers(n uint32) ([]User, error) {
 }
 
 // LastUser find the last one user by ID DESC order.
-func LastUser() (*User, error) {
+func (s *Store) LastUser() (*User, error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	_user := User{}
-	err := DB.Get(&_user, DB.Rebind(`SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users ORDER BY users.id DESC LIMIT 1`))
+	err = db.Get(&_user, db.Rebind("SELECT "+s.userCols()+` FROM users ORDER BY users.id DESC LIMIT 1`))
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -262,10 +313,14 @@ func LastUser() (*User, error) {
 }
 
 // LastUsers find the last N users by ID DESC order.
-func LastUsers(n uint32) ([]User, error) {
+func (s *Store) LastUsers(n uint32) ([]User, error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	_users := []User{}
-	sql := fmt.Sprintf("SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users ORDER BY users.id DESC LIMIT %v", n)
-	err := DB.Select(&_users, DB.Rebind(sql))
+	sql := fmt.Sprintf("SELECT "+s.userCols()+" FROM users ORDER BY users.id DESC LIMIT %v", n)
+	err = db.Select(&_users, db.Rebind(sql))
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -274,20 +329,24 @@ func LastUsers(n uint32) ([]User, error) {
 }
 
 // FindUsers find one or more users by the given ID(s).
-func FindUsers(ids ...int64) ([]User, error) {
+func (s *Store) FindUsers(ids ...int64) ([]User, error) {
 	if len(ids) == 0 {
 		msg := "At least one or more ids needed"
 		log.Println(msg)
 		return nil, errors.New(msg)
 	}
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	_users := []User{}
 	idsHolder := strings.Repeat(",?", len(ids)-1)
-	sql := DB.Rebind(fmt.Sprintf(`SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users WHERE users.id IN (?%s)`, idsHolder))
+	sql := db.Rebind(fmt.Sprintf("SELECT "+s.userCols()+` FROM users WHERE users.id IN (?%s)`, idsHolder))
 	idsT := []interface{}{}
 	for _, id := range ids {
 		idsT = append(idsT, interface{}(id))
 	}
-	err := DB.Select(&_users, sql, idsT...)
+	err = db.Select(&_users, sql, idsT...)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -296,11 +355,15 @@ func FindUsers(ids ...int64) ([]User, error) {
 }
 
 // FindUserBy find a single user by a field name and a value.
-func FindUserBy(field string, val interface{}) (*User, error) {
+func (s *Store) FindUserBy(field string, val interface{}) (*User, error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	_user := User{}
-	sqlFmt := `SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users WHERE %s = ? LIMIT 1`
+	sqlFmt := "SELECT " + s.userCols() + ` FROM users WHERE %s = ? LIMIT 1`
 	sqlStr := fmt.Sprintf(sqlFmt, field)
-	err := DB.Get(&_user, DB.Rebind(sqlStr), val)
+	err = db.Get(&_user, db.Rebind(sqlStr), val)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -309,10 +372,14 @@ func FindUserBy(field string, val interface{}) (*User, error) {
 }
 
 // FindUsersBy find all users by a field name and a value.
-func FindUsersBy(field string, val interface{}) (_users []User, err error) {
-	sqlFmt := `SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users WHERE %s = ?`
+func (s *Store) FindUsersBy(field string, val interface{}) (_users []User, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	sqlFmt := "SELECT " + s.userCols() + ` FROM users WHERE %s = ?`
 	sqlStr := fmt.Sprintf(sqlFmt, field)
-	err = DB.Select(&_users, DB.Rebind(sqlStr), val)
+	err = db.Select(&_users, db.Rebind(sqlStr), val)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return nil, err
@@ -321,8 +388,12 @@ func FindUsersBy(field string, val interface{}) (_users []User, err error) {
 }
 
 // AllUsers get all the User records.
-func AllUsers() (users []User, err error) {
-	err = DB.Select(&users, "SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users")
+func (s *Store) AllUsers() (users []User, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&users, "SELECT "+s.userCols()+" FROM users")
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -331,8 +402,12 @@ func AllUsers() (users []User, err error) {
 }
 
 // UserCount get the count of all the User records.
-func UserCount() (c int64, err error) {
-	err = DB.Get(&c, "SELECT count(*) FROM users")
+func (s *Store) UserCount() (c int64, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return 0, err
+	}
+	err = db.Get(&c, "SELECT count(*) FROM users")
 	if err != nil {
 		log.Println(err)
 		return 0, err
@@ -341,12 +416,16 @@ func UserCount() (c int64, err error) {
 }
 
 // UserCountWhere get the count of all the User records with a where clause.
-func UserCountWhere(where string, args ...interface{}) (c int64, err error) {
+func (s *Store) UserCountWhere(where string, args ...interface{}) (c int64, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return 0, err
+	}
 	sql := "SELECT count(*) FROM users"
 	if len(where) > 0 {
 		sql = sql + " WHERE " + where
 	}
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+	stmt, err := db.Preparex(db.Rebind(sql))
 	if err != nil {
 		log.Println(err)
 		return 0, err
@@ -360,8 +439,8 @@ func UserCountWhere(where string, args ...interface{}) (c int64, err error) {
 }
 
 // UserIncludesWhere get the User associated models records, currently it's not same as the corresponding "includes" function but "preload" instead in Ruby on Rails. It means that the "sql" should be restricted on User model.
-func UserIncludesWhere(assocs []string, sql string, args ...interface{}) (_users []User, err error) {
-	_users, err = FindUsersWhere(sql, args...)
+func (s *Store) UserIncludesWhere(assocs []string, sql string, args ...interface{}) (_users []User, err error) {
+	_users, err = s.FindUsersWhere(sql, args...)
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -381,8 +460,12 @@ func UserIncludesWhere(assocs []string, sql string, args ...interface{}) (_users
 }
 
 // UserIds get all the IDs of User records.
-func UserIds() (ids []int64, err error) {
-	err = DB.Select(&ids, "SELECT id FROM users")
+func (s *Store) UserIds() (ids []int64, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&ids, "SELECT id FROM users")
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -391,18 +474,22 @@ func UserIds() (ids []int64, err error) {
 }
 
 // UserIdsWhere get all the IDs of User records by where restriction.
-func UserIdsWhere(where string, args ...interface{}) ([]int64, error) {
-	ids, err := UserIntCol("id", where, args...)
+func (s *Store) UserIdsWhere(where string, args ...interface{}) ([]int64, error) {
+	ids, err := s.UserIntCol("id", where, args...)
 	return ids, err
 }
 
 // UserIntCol get some int64 typed column of User by where restriction.
-func UserIntCol(col, where string, args ...interface{}) (intColRecs []int64, err error) {
+func (s *Store) UserIntCol(col, where string, args ...interface{}) (intColRecs []int64, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	sql := "SELECT " + col + " FROM users"
 	if len(where) > 0 {
 		sql = sql + " WHERE " + where
 	}
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+	stmt, err := db.Preparex(db.Rebind(sql))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -416,12 +503,16 @@ func UserIntCol(col, where string, args ...interface{}) (intColRecs []int64, err
 }
 
 // UserStrCol get some string typed column of User by where restriction.
-func UserStrCol(col, where string, args ...interface{}) (strColRecs []string, err error) {
+func (s *Store) UserStrCol(col, where string, args ...interface{}) (strColRecs []string, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
 	sql := "SELECT " + col + " FROM users"
 	if len(where) > 0 {
 		sql = sql + " WHERE " + where
 	}
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+	stmt, err := db.Preparex(db.Rebind(sql))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -437,12 +528,16 @@ func UserStrCol(col, where string, args ...interface{}) (strColRecs []string, er
 // FindUsersWhere query use a partial SQL clause that usually following after WHERE
 // with placeholders, eg: FindUsersWhere("first_name = ? AND age > ?", "John", 18)
 // will return those records in the table "users" whose first_name is "John" and age elder than 18.
-func FindUsersWhere(where string, args ...interface{}) (users []User, err error) {
-	sql := "SELECT COALESCE(users.reset_password_token, '') AS reset_password_token, COALESCE(users.reset_password_sent_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS reset_password_sent_at, COALESCE(users.remember_created_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS remember_created_at, COALESCE(users.current_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS current_sign_in_at, COALESCE(users.last_sign_in_at, CONVERT_TZ('0001-01-01 00:00:00','+00:00','UTC')) AS last_sign_in_at, COALESCE(users.current_sign_in_ip, '') AS current_sign_in_ip, COALESCE(users.last_sign_in_ip, '') AS last_sign_in_ip, users.id, users.email, users.encrypted_password, users.sign_in_count, users.created_at, users.updated_at FROM users"
+func (s *Store) FindUsersWhere(where string, args ...interface{}) (users []User, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	sql := "SELECT " + s.userCols() + " FROM users"
 	if len(where) > 0 {
 		sql = sql + " WHERE " + where
 	}
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+	stmt, err := db.Preparex(db.Rebind(sql))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -458,8 +553,12 @@ func FindUsersWhere(where string, args ...interface{}) (users []User, err error)
 // FindUserBySql query use a complete SQL clause
 // with placeholders, eg: FindUserBySql("SELECT * FROM users WHERE first_name = ? AND age > ? ORDER BY DESC LIMIT 1", "John", 18)
 // will return only One record in the table "users" whose first_name is "John" and age elder than 18.
-func FindUserBySql(sql string, args ...interface{}) (*User, error) {
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+func (s *Store) FindUserBySql(sql string, args ...interface{}) (*User, error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := db.Preparex(db.Rebind(sql))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -476,8 +575,12 @@ func FindUserBySql(sql string, args ...interface{}) (*User, error) {
 // FindUsersBySql query use a complete SQL clause
 // with placeholders, eg: FindUsersBySql("SELECT * FROM users WHERE first_name = ? AND age > ?", "John", 18)
 // will return those records in the table "users" whose first_name is "John" and age elder than 18.
-func FindUsersBySql(sql string, args ...interface{}) (users []User, err error) {
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+func (s *Store) FindUsersBySql(sql string, args ...interface{}) (users []User, err error) {
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := db.Preparex(db.Rebind(sql))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -492,25 +595,25 @@ func FindUsersBySql(sql string, args ...interface{}) (users []User, err error) {
 
 // CreateUser use a named params to create a single User record.
 // A named params is key-value map like map[string]interface{}{"first_name": "John", "age": 23} .
-func CreateUser(am map[string]interface{}) (int64, error) {
+func (s *Store) CreateUser(am map[string]interface{}) (int64, error) {
 	if len(am) == 0 {
 		return 0, fmt.Errorf("Zero key in the attributes map!")
 	}
-	t := time.Now()
+	db, err := s.conn()
+	if err != nil {
+		return 0, err
+	}
+	t := time.Now().UTC()
 	for _, v := range []string{"created_at", "updated_at"} {
 		if am[v] == nil {
 			am[v] = t
 		}
 	}
+	nullUserIPs(am)
 	keys := allKeys(am)
 	sqlFmt := `INSERT INTO users (%s) VALUES (%s)`
 	sql := fmt.Sprintf(sqlFmt, strings.Join(keys, ","), ":"+strings.Join(keys, ",:"))
-	result, err := DB.NamedExec(sql, am)
-	if err != nil {
-		log.Println(err)
-		return 0, err
-	}
-	lastId, err := result.LastInsertId()
+	lastId, err := insertReturningID(db, s.dialect, sql, am)
 	if err != nil {
 		log.Println(err)
 		return 0, err
@@ -520,6 +623,11 @@ func CreateUser(am map[string]interface{}) (int64, error) {
 
 // Create is a method for User to create a record.
 func (_user *User) Create() (int64, error) {
+	return defaultStore.InsertUser(_user)
+}
+
+// InsertUser creates the record of a User.
+func (s *Store) InsertUser(_user *User) (int64, error) {
 	ok, err := govalidator.ValidateStruct(_user)
 	if !ok {
 		errMsg := "Validate User struct error: Unknown error"
@@ -529,16 +637,15 @@ func (_user *User) Create() (int64, error) {
 		log.Println(errMsg)
 		return 0, errors.New(errMsg)
 	}
-	t := time.Now()
+	t := time.Now().UTC()
 	_user.CreatedAt = t
 	_user.UpdatedAt = t
-	sql := `INSERT INTO users (email,encrypted_password,reset_password_token,reset_password_sent_at,remember_created_at,sign_in_count,current_sign_in_at,last_sign_in_at,current_sign_in_ip,last_sign_in_ip,created_at,updated_at) VALUES (:email,:encrypted_password,:reset_password_token,:reset_password_sent_at,:remember_created_at,:sign_in_count,:current_sign_in_at,:last_sign_in_at,:current_sign_in_ip,:last_sign_in_ip,:created_at,:updated_at)`
-	result, err := DB.NamedExec(sql, _user)
+	db, err := s.conn()
 	if err != nil {
-		log.Println(err)
 		return 0, err
 	}
-	lastId, err := result.LastInsertId()
+	sql := `INSERT INTO users (email,encrypted_password,reset_password_token,reset_password_sent_at,remember_created_at,sign_in_count,current_sign_in_at,last_sign_in_at,current_sign_in_ip,last_sign_in_ip,created_at,updated_at) VALUES (:email,:encrypted_password,:reset_password_token,:reset_password_sent_at,:remember_created_at,:sign_in_count,:current_sign_in_at,:last_sign_in_at,:current_sign_in_ip,:last_sign_in_ip,:created_at,:updated_at)`
+	lastId, err := insertReturningID(db, s.dialect, sql, newUserArgs(_user))
 	if err != nil {
 		log.Println(err)
 		return 0, err
@@ -556,8 +663,12 @@ func (_user *User) Destroy() error {
 }
 
 // DestroyUser will destroy a User record specified by the id parameter.
-func DestroyUser(id int64) error {
-	stmt, err := DB.Preparex(DB.Rebind(`DELETE FROM users WHERE id = ?`))
+func (s *Store) DestroyUser(id int64) error {
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	stmt, err := db.Preparex(db.Rebind(`DELETE FROM users WHERE id = ?`))
 	_, err = stmt.Exec(id)
 	if err != nil {
 		return err
@@ -566,19 +677,23 @@ func DestroyUser(id int64) error {
 }
 
 // DestroyUsers will destroy User records those specified by the ids parameters.
-func DestroyUsers(ids ...int64) (int64, error) {
+func (s *Store) DestroyUsers(ids ...int64) (int64, error) {
 	if len(ids) == 0 {
 		msg := "At least one or more ids needed"
 		log.Println(msg)
 		return 0, errors.New(msg)
 	}
+	db, err := s.conn()
+	if err != nil {
+		return 0, err
+	}
 	idsHolder := strings.Repeat(",?", len(ids)-1)
 	sql := fmt.Sprintf(`DELETE FROM users WHERE id IN (?%s)`, idsHolder)
 	idsT := []interface{}{}
 	for _, id := range ids {
 		idsT = append(idsT, interface{}(id))
 	}
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+	stmt, err := db.Preparex(db.Rebind(sql))
 	result, err := stmt.Exec(idsT...)
 	if err != nil {
 		return 0, err
@@ -593,14 +708,18 @@ func DestroyUsers(ids ...int64) (int64, error) {
 // DestroyUsersWhere delete records by a where clause restriction.
 // e.g. DestroyUsersWhere("name = ?", "John")
 // And this func will not call the association dependent action
-func DestroyUsersWhere(where string, args ...interface{}) (int64, error) {
+func (s *Store) DestroyUsersWhere(where string, args ...interface{}) (int64, error) {
+	db, err := s.conn()
+	if err != nil {
+		return 0, err
+	}
 	sql := `DELETE FROM users WHERE `
 	if len(where) > 0 {
 		sql = sql + where
 	} else {
 		return 0, errors.New("No WHERE conditions provided")
 	}
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+	stmt, err := db.Preparex(db.Rebind(sql))
 	result, err := stmt.Exec(args...)
 	if err != nil {
 		return 0, err
@@ -615,6 +734,11 @@ func DestroyUsersWhere(where string, args ...interface{}) (int64, error) {
 // Save method is used for a User object to update an existed record mainly.
 // If no id provided a new record will be created. FIXME: A UPSERT action will be implemented further.
 func (_user *User) Save() error {
+	return defaultStore.SaveUser(_user)
+}
+
+// SaveUser updates the record of a User, or creates it if it has no id.
+func (s *Store) SaveUser(_user *User) error {
 	ok, err := govalidator.ValidateStruct(_user)
 	if !ok {
 		errMsg := "Validate User struct error: Unknown error"
@@ -625,31 +749,40 @@ func (_user *User) Save() error {
 		return errors.New(errMsg)
 	}
 	if _user.Id == 0 {
-		_, err = _user.Create()
+		_, err = s.InsertUser(_user)
 		return err
 	}
-	_user.UpdatedAt = time.Now()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	_user.UpdatedAt = time.Now().UTC()
 	sqlFmt := `UPDATE users SET %s WHERE id = %v`
 	sqlStr := fmt.Sprintf(sqlFmt, "email = :email, encrypted_password = :encrypted_password, reset_password_token = :reset_password_token, reset_password_sent_at = :reset_password_sent_at, remember_created_at = :remember_created_at, sign_in_count = :sign_in_count, current_sign_in_at = :current_sign_in_at, last_sign_in_at = :last_sign_in_at, current_sign_in_ip = :current_sign_in_ip, last_sign_in_ip = :last_sign_in_ip, updated_at = :updated_at", _user.Id)
-	_, err = DB.NamedExec(sqlStr, _user)
+	_, err = db.NamedExec(sqlStr, newUserArgs(_user))
 	return err
 }
 
 // UpdateUser is used to update a record with a id and map[string]interface{} typed key-value parameters.
-func UpdateUser(id int64, am map[string]interface{}) error {
+func (s *Store) UpdateUser(id int64, am map[string]interface{}) error {
 	if len(am) == 0 {
 		return errors.New("Zero key in the attributes map!")
 	}
-	am["updated_at"] = time.Now()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	am["updated_at"] = time.Now().UTC()
+	nullUserIPs(am)
 	keys := allKeys(am)
 	sqlFmt := `UPDATE users SET %s WHERE id = %v`
 	setKeysArr := []string{}
 	for _, v := range keys {
-		s := fmt.Sprintf(" %s = :%s", v, v)
-		setKeysArr = append(setKeysArr, s)
+		set := fmt.Sprintf(" %s = :%s", v, v)
+		setKeysArr = append(setKeysArr, set)
 	}
 	sqlStr := fmt.Sprintf(sqlFmt, strings.Join(setKeysArr, ", "), id)
-	_, err := DB.NamedExec(sqlStr, am)
+	_, err = db.NamedExec(sqlStr, am)
 	if err != nil {
 		log.Println(err)
 		return err
@@ -686,11 +819,15 @@ func (_user *User) UpdateColumns(am map[string]interface{}) error {
 
 // UpdateUsersBySql is used to update User records by a SQL clause
 // using the '?' binding syntax.
-func UpdateUsersBySql(sql string, args ...interface{}) (int64, error) {
+func (s *Store) UpdateUsersBySql(sql string, args ...interface{}) (int64, error) {
 	if sql == "" {
 		return 0, errors.New("A blank SQL clause")
 	}
-	stmt, err := DB.Preparex(DB.Rebind(sql))
+	db, err := s.conn()
+	if err != nil {
+		return 0, err
+	}
+	stmt, err := db.Preparex(db.Rebind(sql))
 	result, err := stmt.Exec(args...)
 	if err != nil {
 		return 0, err
@@ -701,3 +838,131 @@ func UpdateUsersBySql(sql string, args ...interface{}) (int64, error) {
 	}
 	return cnt, nil
 }
+
+// The functions of the User model, on the default store.
+
+// FindUser find a single user by an ID.
+func FindUser(id int64) (*User, error) {
+	return defaultStore.FindUser(id)
+}
+
+// FirstUser find the first one user by ID ASC order.
+func FirstUser() (*User, error) {
+	return defaultStore.FirstUser()
+}
+
+// FirstUsers find the first N users by ID ASC order.
+func FirstUsers(n uint32) ([]User, error) {
+	return defaultStore.FirstUsers(n)
+}
+
+// LastUser find the last one user by ID DESC order.
+func LastUser() (*User, error) {
+	return defaultStore.LastUser()
+}
+
+// LastUsers find the last N users by ID DESC order.
+func LastUsers(n uint32) ([]User, error) {
+	return defaultStore.LastUsers(n)
+}
+
+// FindUsers find one or more users by the given ID(s).
+func FindUsers(ids ...int64) ([]User, error) {
+	return defaultStore.FindUsers(ids...)
+}
+
+// FindUserBy find a single user by a field name and a value.
+func FindUserBy(field string, val interface{}) (*User, error) {
+	return defaultStore.FindUserBy(field, val)
+}
+
+// FindUsersBy find all users by a field name and a value.
+func FindUsersBy(field string, val interface{}) ([]User, error) {
+	return defaultStore.FindUsersBy(field, val)
+}
+
+// AllUsers get all the User records.
+func AllUsers() ([]User, error) {
+	return defaultStore.AllUsers()
+}
+
+// UserCount get the count of all the User records.
+func UserCount() (int64, error) {
+	return defaultStore.UserCount()
+}
+
+// UserCountWhere get the count of all the User records with a where clause.
+func UserCountWhere(where string, args ...interface{}) (int64, error) {
+	return defaultStore.UserCountWhere(where, args...)
+}
+
+// UserIncludesWhere get the User associated models records.
+func UserIncludesWhere(assocs []string, sql string, args ...interface{}) ([]User, error) {
+	return defaultStore.UserIncludesWhere(assocs, sql, args...)
+}
+
+// UserIds get all the IDs of User records.
+func UserIds() ([]int64, error) {
+	return defaultStore.UserIds()
+}
+
+// UserIdsWhere get all the IDs of User records by where restriction.
+func UserIdsWhere(where string, args ...interface{}) ([]int64, error) {
+	return defaultStore.UserIdsWhere(where, args...)
+}
+
+// UserIntCol get some int64 typed column of User by where restriction.
+func UserIntCol(col, where string, args ...interface{}) ([]int64, error) {
+	return defaultStore.UserIntCol(col, where, args...)
+}
+
+// UserStrCol get some string typed column of User by where restriction.
+func UserStrCol(col, where string, args ...interface{}) ([]string, error) {
+	return defaultStore.UserStrCol(col, where, args...)
+}
+
+// FindUsersWhere query use a partial SQL clause that usually following after WHERE.
+func FindUsersWhere(where string, args ...interface{}) ([]User, error) {
+	return defaultStore.FindUsersWhere(where, args...)
+}
+
+// FindUserBySql query use a complete SQL clause, returning one record.
+func FindUserBySql(sql string, args ...interface{}) (*User, error) {
+	return defaultStore.FindUserBySql(sql, args...)
+}
+
+// FindUsersBySql query use a complete SQL clause.
+func FindUsersBySql(sql string, args ...interface{}) ([]User, error) {
+	return defaultStore.FindUsersBySql(sql, args...)
+}
+
+// CreateUser use a named params to create a single User record.
+func CreateUser(am map[string]interface{}) (int64, error) {
+	return defaultStore.CreateUser(am)
+}
+
+// DestroyUser will destroy a User record specified by the id parameter.
+func DestroyUser(id int64) error {
+	return defaultStore.DestroyUser(id)
+}
+
+// DestroyUsers will destroy User records those specified by the ids parameters.
+func DestroyUsers(ids ...int64) (int64, error) {
+	return defaultStore.DestroyUsers(ids...)
+}
+
+// DestroyUsersWhere delete records by a where clause restriction.
+func DestroyUsersWhere(where string, args ...interface{}) (int64, error) {
+	return defaultStore.DestroyUsersWhere(where, args...)
+}
+
+// UpdateUser is used to update a record with a id and map[string]interface{} typed key-value parameters.
+func UpdateUser(id int64, am map[string]interface{}) error {
+	return defaultStore.UpdateUser(id, am)
+}
+
+// UpdateUsersBySql is used to update User records by a SQL clause
+// using the '?' binding syntax.
+func UpdateUsersBySql(sql string, args ...interface{}) (int64, error) {
+	return defaultStore.UpdateUsersBySql(sql, args...)
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoDatabase is returned by the default store before Open is called.
+var ErrNoDatabase = errors.New("models: no database, call Open first")
+
+// Store runs the queries of the models on a database. It connects on its first
+// query, retrying with a backoff while the database isn't up yet, so the
+// models can be used without a live database until then. The concurrent
+// queries wait for the same attempts, and once they failed the queries fail
+// right away with their error for MaxRetryDelay before connecting again.
+//
+// The package functions, e.g. FindUser, are kept as wrappers of the default
+// store set by Open.
+type Store struct {
+	// Retries is the number of connection attempts of a query, 5 by default.
+	Retries int
+	// RetryDelay is the delay after the first failed attempt, doubled after
+	// each attempt up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+
+	mu        sync.Mutex
+	db        *sqlx.DB
+	dialect   dialect
+	connected bool
+	// connecting is closed when the attempts in progress are over
+	connecting chan struct{}
+	// connErr is the error of the last failed attempts, returned until retryAt
+	connErr error
+	retryAt time.Time
+}
+
+// UserRepository is the storage of the users, the controllers use it so they
+// can be given another one than a *Store in tests.
+type UserRepository interface {
+	FindUser(id int64) (*User, error)
+	FirstUser() (*User, error)
+	FirstUsers(n uint32) ([]User, error)
+	LastUser() (*User, error)
+	LastUsers(n uint32) ([]User, error)
+	FindUsers(ids ...int64) ([]User, error)
+	FindUserBy(field string, val interface{}) (*User, error)
+	FindUsersBy(field string, val interface{}) ([]User, error)
+	FindUsersWhere(where string, args ...interface{}) ([]User, error)
+	FindUserBySql(sql string, args ...interface{}) (*User, error)
+	FindUsersBySql(sql string, args ...interface{}) ([]User, error)
+	FindUserForAuthentication(email string) (*User, error)
+	AllUsers() ([]User, error)
+	UserCount() (int64, error)
+	UserCountWhere(where string, args ...interface{}) (int64, error)
+	UserIds() ([]int64, error)
+	UserIdsWhere(where string, args ...interface{}) ([]int64, error)
+	CreateUser(am map[string]interface{}) (int64, error)
+	InsertUser(user *User) (int64, error)
+	SaveUser(user *User) error
+	UpdateUser(id int64, am map[string]interface{}) error
+	UpdateUsersBySql(sql string, args ...interface{}) (int64, error)
+	DestroyUser(id int64) error
+	DestroyUsers(ids ...int64) (int64, error)
+	DestroyUsersWhere(where string, args ...interface{}) (int64, error)
+	TrackSignIn(user *User, ip string, at time.Time) error
+	ForgetMe(user *User) error
+	RequestPasswordReset(email string) (*User, string, error)
+	ResetPassword(rawToken, newPassword string) (*User, error)
+}
+
+var _ UserRepository = (*Store)(nil)
+
+// defaultStore is the store of the package functions
+var defaultStore = &Store{}
+
+// NewStore returns a store on a database opened by the caller, the SQL is
+// written for its driver. Use OpenStore for SQLite, its timestamps need the
+// connections opened by the models.
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db, dialect: dialects[driverName(db.DriverName())]}
+}
+
+// OpenStore returns a store on the database of a driver or a Rails adapter,
+// it isn't connected until its first query.
+func OpenStore(driver, dsn string) (*Store, error) {
+	db, d, err := connect(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, dialect: d}, nil
+}
+
+// Open makes the database the one of the default store, the driver is the
+// name of a database/sql driver (mysql, postgres or sqlite3) or of a Rails
+// adapter, e.g. "postgresql", and the dsn its data source name, see
+// config.Database. The database isn't connected until the first query.
+func Open(driver, dsn string) error {
+	s, err := OpenStore(driver, dsn)
+	if err != nil {
+		return err
+	}
+	SetDefault(s)
+	return nil
+}
+
+// Default returns the store of the package functions.
+func Default() *Store {
+	return defaultStore
+}
+
+// SetDefault makes the package functions use the database of a store.
+func SetDefault(s *Store) {
+	s.mu.Lock()
+	db, d := s.db, s.dialect
+	s.mu.Unlock()
+	defaultStore.mu.Lock()
+	defer defaultStore.mu.Unlock()
+	defaultStore.db, defaultStore.dialect, defaultStore.connected = db, d, false
+	defaultStore.connErr = nil
+}
+
+// DB returns the database of the store, it may not be connected yet.
+func (s *Store) DB() *sqlx.DB {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db
+}
+
+// conn returns the database once it's connected, the first call tries to
+// connect a few times, waiting more after each failure. The lock isn't held
+// while it waits, the other calls wait for the result of the same attempts.
+func (s *Store) conn() (*sqlx.DB, error) {
+	s.mu.Lock()
+	if s.db == nil {
+		s.mu.Unlock()
+		return nil, ErrNoDatabase
+	}
+	if s.connected {
+		db := s.db
+		s.mu.Unlock()
+		return db, nil
+	}
+	if s.connErr != nil && time.Now().Before(s.retryAt) {
+		err := s.connErr
+		s.mu.Unlock()
+		return nil, err
+	}
+	if done := s.connecting; done != nil {
+		s.mu.Unlock()
+		<-done
+		return s.connResult()
+	}
+	done := make(chan struct{})
+	s.connecting = done
+	db := s.db
+	s.mu.Unlock()
+
+	err := s.connect(db)
+	s.mu.Lock()
+	s.connecting = nil
+	// the database may have been replaced or closed meanwhile
+	if s.db == db {
+		s.connected, s.connErr = err == nil, err
+		if err != nil {
+			s.retryAt = time.Now().Add(s.maxRetryDelay())
+		}
+	}
+	s.mu.Unlock()
+	close(done)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// connResult returns the database or the error of the last attempts.
+func (s *Store) connResult() (*sqlx.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.db == nil:
+		return nil, ErrNoDatabase
+	case s.connected:
+		return s.db, nil
+	case s.connErr != nil:
+		return nil, s.connErr
+	}
+	return nil, errors.New("models: database not connected")
+}
+
+// connect pings the database up to Retries times with the backoff.
+func (s *Store) connect(db *sqlx.DB) error {
+	retries, delay, maxDelay := s.Retries, s.RetryDelay, s.maxRetryDelay()
+	if retries <= 0 {
+		retries = 5
+	}
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = db.Ping(); err == nil {
+			return nil
+		}
+		if attempt == retries {
+			return err
+		}
+		log.Printf("connect database err (attempt %d of %d): %v", attempt, retries, err)
+		time.Sleep(delay)
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+func (s *Store) maxRetryDelay() time.Duration {
+	if s.MaxRetryDelay <= 0 {
+		return 5 * time.Second
+	}
+	return s.MaxRetryDelay
+}
+
+// Ping checks that the database answers, without retrying.
+func (s *Store) Ping(ctx context.Context) error {
+	db := s.DB()
+	if db == nil {
+		return ErrNoDatabase
+	}
+	return db.PingContext(ctx)
+}
+
+// Close closes the connections of the store.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	s.connected, s.connErr = false, nil
+	return s.db.Close()
+}
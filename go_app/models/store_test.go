@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// downDriver is a database driver counting its connections, they fail while
+// down is set.
+type downDriver struct {
+	opens int32
+	down  int32
+}
+
+var errDown = errors.New("database is down")
+
+func (d *downDriver) Open(string) (driver.Conn, error) {
+	atomic.AddInt32(&d.opens, 1)
+	if atomic.LoadInt32(&d.down) != 0 {
+		return nil, errDown
+	}
+	return downConn{}, nil
+}
+
+type downConn struct{}
+
+func (downConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (downConn) Close() error                        { return nil }
+func (downConn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+var testDriver = &downDriver{}
+
+func init() {
+	sql.Register("models-down", testDriver)
+}
+
+func TestStoreConn(t *testing.T) {
+	atomic.StoreInt32(&testDriver.opens, 0)
+	atomic.StoreInt32(&testDriver.down, 1)
+	db, err := sqlx.Open("models-down", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s := NewStore(db)
+	s.Retries, s.RetryDelay, s.MaxRetryDelay = 3, 20*time.Millisecond, 200*time.Millisecond
+
+	// the concurrent calls share the same attempts
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.conn(); err != errDown {
+				t.Errorf("conn err = %v, want %v", err, errDown)
+			}
+		}()
+	}
+	// the lock isn't held during the attempts
+	time.Sleep(5 * time.Millisecond)
+	start := time.Now()
+	s.DB()
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("DB blocked for %v while connecting", d)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&testDriver.opens); n != 3 {
+		t.Errorf("connection attempts = %d, want 3", n)
+	}
+
+	// within the backoff the calls fail right away
+	atomic.StoreInt32(&testDriver.down, 0)
+	start = time.Now()
+	if _, err := s.conn(); err != errDown {
+		t.Errorf("conn in the backoff err = %v, want %v", err, errDown)
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("conn in the backoff took %v", d)
+	}
+	if n := atomic.LoadInt32(&testDriver.opens); n != 3 {
+		t.Errorf("connection attempts in the backoff = %d, want 3", n)
+	}
+
+	time.Sleep(s.MaxRetryDelay)
+	if got, err := s.conn(); err != nil || got != db {
+		t.Errorf("conn after the backoff = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"../config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The functions in this file aren't generated by go-on-rails, they implement
+// the parts of Devise the Go app needs to share the users with Rails.
+
+// Devise are the Devise settings of the Rails app the User functions follow,
+// replace them with the ones read by config.Rails.Devise.
+var Devise = config.DefaultDevise()
+
+// AuthenticatableSalt is the salt Devise stores in the session along with the
+// user id, so that changing the password signs out all the other sessions.
+func (_user *User) AuthenticatableSalt() string {
+	if len(_user.EncryptedPassword) < 29 {
+		return _user.EncryptedPassword
+	}
+	return _user.EncryptedPassword[:29]
+}
+
+// ValidPassword checks a password against the bcrypt hash in EncryptedPassword,
+// like Devise's valid_password?, the pepper is appended to the password.
+func (_user *User) ValidPassword(password string) bool {
+	if _user.EncryptedPassword == "" {
+		return false
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(_user.EncryptedPassword), bcryptSecret(password))
+	return err == nil
+}
+
+// bcryptSecret is the password and the pepper as hashed by the bcrypt gem,
+// which only uses their first 72 bytes where golang.org/x/crypto/bcrypt
+// refuses longer secrets.
+func bcryptSecret(password string) []byte {
+	secret := []byte(password + Devise.Pepper)
+	if len(secret) > 72 {
+		secret = secret[:72]
+	}
+	return secret
+}
+
+// SetPassword hashes a password into EncryptedPassword with the Devise stretches
+// as bcrypt cost, the record isn't saved.
+func (_user *User) SetPassword(password string) error {
+	if password == "" {
+		return PasswordError("Password can't be blank")
+	}
+	cost := Devise.Stretches
+	if cost < bcrypt.MinCost {
+		// the bcrypt gem raises lower costs to its minimum as well
+		cost = bcrypt.MinCost
+	}
+	hash, err := bcrypt.GenerateFromPassword(bcryptSecret(password), cost)
+	if err != nil {
+		return err
+	}
+	_user.EncryptedPassword = string(hash)
+	return nil
+}
+
+// FindUserForAuthentication finds the user signing in by email, the email is
+// downcased and stripped as configured in Devise's case_insensitive_keys and
+// strip_whitespace_keys.
+func FindUserForAuthentication(email string) (*User, error) {
+	return defaultStore.FindUserForAuthentication(email)
+}
+
+// FindUserForAuthentication is the package FindUserForAuthentication on the store.
+func (s *Store) FindUserForAuthentication(email string) (*User, error) {
+	if containsKey(Devise.StripWhitespaceKeys, "email") {
+		email = strings.TrimSpace(email)
+	}
+	if containsKey(Devise.CaseInsensitiveKeys, "email") {
+		email = strings.ToLower(email)
+	}
+	if email == "" {
+		return nil, sql.ErrNoRows
+	}
+	return s.FindUserBy("email", email)
+}
+
+// TrackSignIn updates the columns of Devise's trackable module for a sign-in
+// from the ip at the time: the current sign-in becomes the last one, the
+// counter is incremented and the new sign-in is stamped. It's done in a single
+// UPDATE so concurrent sign-ins can't lose a count, the user is then reloaded.
+func (_user *User) TrackSignIn(ip string, at time.Time) error {
+	return defaultStore.TrackSignIn(_user, ip, at)
+}
+
+// TrackSignIn is the User's TrackSignIn on the store.
+func (s *Store) TrackSignIn(_user *User, ip string, at time.Time) error {
+	if _user.Id == 0 {
+		return errors.New("Invalid Id field: it can't be a zero value")
+	}
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	at = at.UTC()
+	// the last_* columns are set first as MySQL assigns from left to right
+	// with the updated values, the other databases use the old values anyway.
+	// The CASE keeps the type of the column, a string or an inet.
+	sqlStr := `UPDATE users SET last_sign_in_at = COALESCE(current_sign_in_at, ?), last_sign_in_ip = CASE WHEN ` + s.dialect.ip("current_sign_in_ip") + ` IS NULL OR ` + s.dialect.ip("current_sign_in_ip") + ` = '' THEN ? ELSE current_sign_in_ip END, current_sign_in_at = ?, current_sign_in_ip = ?, sign_in_count = sign_in_count + 1, updated_at = ? WHERE id = ?`
+	_, err = db.Exec(db.Rebind(sqlStr), at, nullIP(ip), at, nullIP(ip), time.Now().UTC(), _user.Id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	u, err := s.FindUser(_user.Id)
+	if err != nil {
+		return err
+	}
+	_user.SignInCount = u.SignInCount
+	_user.CurrentSignInAt, _user.CurrentSignInIp = u.CurrentSignInAt, u.CurrentSignInIp
+	_user.LastSignInAt, _user.LastSignInIp = u.LastSignInAt, u.LastSignInIp
+	_user.UpdatedAt = u.UpdatedAt
+	return nil
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"../session"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// The functions in this file implement Devise's recoverable module, the reset
+// password tokens are interchangeable with the ones of the Rails app: the raw
+// token is emailed and only its HMAC digest is stored in reset_password_token.
+
+var (
+	ErrInvalidResetToken = errors.New("Reset password token is invalid")
+	ErrExpiredResetToken = errors.New("Reset password token has expired, please request a new one")
+)
+
+// PasswordError is the validation error of a new password.
+type PasswordError string
+
+func (e PasswordError) Error() string {
+	return string(e)
+}
+
+// RequestPasswordReset generates a reset password token for the user with the
+// email, like Devise's send_reset_password_instructions. The raw token is
+// returned to be emailed, the user is nil with sql.ErrNoRows for an unknown email.
+func RequestPasswordReset(email string) (*User, string, error) {
+	return defaultStore.RequestPasswordReset(email)
+}
+
+// RequestPasswordReset is the package RequestPasswordReset on the store.
+func (s *Store) RequestPasswordReset(email string) (*User, string, error) {
+	user, err := s.FindUserForAuthentication(email)
+	if err != nil {
+		return nil, "", err
+	}
+	db, err := s.conn()
+	if err != nil {
+		return nil, "", err
+	}
+	raw, enc, err := s.generateToken("reset_password_token")
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	sqlStr := `UPDATE users SET reset_password_token = ?, reset_password_sent_at = ?, updated_at = ? WHERE id = ?`
+	if _, err := db.Exec(db.Rebind(sqlStr), enc, now, now, user.Id); err != nil {
+		log.Println(err)
+		return nil, "", err
+	}
+	user.ResetPasswordToken, user.ResetPasswordSentAt, user.UpdatedAt = enc, now, now
+	return user, raw, nil
+}
+
+// ResetPassword sets a new password for the user the raw token was sent to,
+// like Devise's reset_password_by_token. The token expires after
+// reset_password_within and it's cleared with the new password, so it can be
+// used once only.
+func ResetPassword(rawToken, newPassword string) (*User, error) {
+	return defaultStore.ResetPassword(rawToken, newPassword)
+}
+
+// ResetPassword is the package ResetPassword on the store.
+func (s *Store) ResetPassword(rawToken, newPassword string) (*User, error) {
+	if rawToken == "" {
+		return nil, ErrInvalidResetToken
+	}
+	user, err := s.FindUserBy("reset_password_token", tokenDigest("reset_password_token", rawToken))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidResetToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.ResetPasswordPeriodValid() {
+		return nil, ErrExpiredResetToken
+	}
+	if err := ValidatePasswordLength(newPassword); err != nil {
+		return nil, err
+	}
+	if err := user.SetPassword(newPassword); err != nil {
+		return nil, err
+	}
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sqlStr := `UPDATE users SET encrypted_password = ?, reset_password_token = NULL, reset_password_sent_at = NULL, updated_at = ? WHERE id = ?`
+	if _, err := db.Exec(db.Rebind(sqlStr), user.EncryptedPassword, now, user.Id); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	user.ResetPasswordToken, user.ResetPasswordSentAt, user.UpdatedAt = "", time.Time{}, now
+	return user, nil
+}
+
+// ResetPasswordPeriodValid tells if the reset password token was sent within
+// Devise's reset_password_within.
+func (_user *User) ResetPasswordPeriodValid() bool {
+	if _user.ResetPasswordSentAt.IsZero() {
+		return false
+	}
+	return time.Now().Before(_user.ResetPasswordSentAt.Add(Devise.ResetPasswordWithin))
+}
+
+// ValidatePasswordLength checks a password against Devise's password_length,
+// the length is counted in characters as Ruby does.
+func ValidatePasswordLength(password string) error {
+	switch n := utf8.RuneCountInString(password); {
+	case n == 0:
+		return PasswordError("Password can't be blank")
+	case n < Devise.MinPasswordLength:
+		return PasswordError(fmt.Sprintf("Password is too short (minimum is %d characters)", Devise.MinPasswordLength))
+	case Devise.MaxPasswordLength > 0 && n > Devise.MaxPasswordLength:
+		return PasswordError(fmt.Sprintf("Password is too long (maximum is %d characters)", Devise.MaxPasswordLength))
+	}
+	return nil
+}
+
+// generateToken returns a raw token and its digest that isn't stored in the
+// column yet, like Devise::TokenGenerator#generate.
+func (s *Store) generateToken(column string) (raw, enc string, err error) {
+	for {
+		if raw, err = friendlyToken(); err != nil {
+			return "", "", err
+		}
+		enc = tokenDigest(column, raw)
+		n, err := s.UserCountWhere(column+" = ?", enc)
+		if err != nil {
+			return "", "", err
+		}
+		if n == 0 {
+			return raw, enc, nil
+		}
+	}
+}
+
+// friendlyToken is Devise.friendly_token: 20 url safe characters without the
+// ambiguous l, I, O and 0.
+func friendlyToken() (string, error) {
+	b := make([]byte, 15)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.URLEncoding.EncodeToString(b)
+	return strings.NewReplacer("l", "s", "I", "x", "O", "y", "0", "z").Replace(token), nil
+}
+
+// tokenDigest is Devise::TokenGenerator#digest, the HMAC-SHA256 of the raw
+// token with a key generated for the column.
+func tokenDigest(column, raw string) string {
+	mac := hmac.New(sha256.New, tokenKey(column))
+	mac.Write([]byte(raw))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type tokenKeyID struct {
+	secret, column string
+	digest         session.Digest
+}
+
+// tokenKeys caches the keys like ActiveSupport::CachingKeyGenerator,
+// the 65536 PBKDF2 iterations are too slow to run on each request.
+var tokenKeys = struct {
+	sync.Mutex
+	m map[tokenKeyID][]byte
+}{m: map[tokenKeyID][]byte{}}
+
+// tokenKey is the key ActiveSupport::KeyGenerator generates from Devise's
+// secret_key with the salt "Devise <column>".
+func tokenKey(column string) []byte {
+	id := tokenKeyID{Devise.SecretKey, column, Devise.KeyDigest}
+	tokenKeys.Lock()
+	defer tokenKeys.Unlock()
+	if key, ok := tokenKeys.m[id]; ok {
+		return key
+	}
+	var h func() hash.Hash = sha1.New
+	if id.digest == session.SHA256 {
+		h = sha256.New
+	}
+	key := pbkdf2.Key([]byte(id.secret), []byte("Devise "+column), 1<<16, 64, h)
+	tokenKeys.m[id] = key
+	return key
+}
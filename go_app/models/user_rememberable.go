@@ -0,0 +1,59 @@
+package models
+
+import (
+	"crypto/subtle"
+	"log"
+	"time"
+)
+
+// The functions in this file implement Devise's rememberable module, the
+// remember cookie itself is read by the controllers.
+
+// RememberableValue is the token stored in the remember cookie, the users
+// table has no remember_token column so it's the authenticatable salt.
+func (_user *User) RememberableValue() string {
+	return _user.AuthenticatableSalt()
+}
+
+// RememberMe tells if a remember cookie with the token generated at the time
+// is still valid for the user, like Devise's remember_me?: it must be younger
+// than remember_for and than remember_created_at, and the token must match.
+func (_user *User) RememberMe(token string, generatedAt time.Time) bool {
+	if generatedAt.IsZero() || token == "" {
+		return false
+	}
+	now := time.Now()
+	createdAt := _user.RememberCreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	return now.Add(-Devise.RememberFor).Before(generatedAt) &&
+		generatedAt.After(createdAt) &&
+		subtle.ConstantTimeCompare([]byte(_user.RememberableValue()), []byte(token)) == 1
+}
+
+// ForgetMe clears remember_created_at when the user signs out, so that the
+// remember cookies of all the browsers are invalid, like Devise's forget_me!.
+// Nothing is done unless expire_all_remember_me_on_sign_out is set.
+func (_user *User) ForgetMe() error {
+	return defaultStore.ForgetMe(_user)
+}
+
+// ForgetMe is the User's ForgetMe on the store.
+func (s *Store) ForgetMe(_user *User) error {
+	if _user.Id == 0 || !Devise.ExpireAllRememberMeOnSignOut {
+		return nil
+	}
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	sqlStr := `UPDATE users SET remember_created_at = NULL, updated_at = ? WHERE id = ?`
+	if _, err := db.Exec(db.Rebind(sqlStr), now, _user.Id); err != nil {
+		log.Println(err)
+		return err
+	}
+	_user.RememberCreatedAt, _user.UpdatedAt = time.Time{}, now
+	return nil
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLongPassword(t *testing.T) {
+	defer func(stretches int, pepper string) { Devise.Stretches, Devise.Pepper = stretches, pepper }(Devise.Stretches, Devise.Pepper)
+	Devise.Stretches = bcrypt.MinCost
+	Devise.Pepper = "pepper"
+
+	// the bcrypt gem hashes the first 72 bytes of the password and the pepper
+	password := strings.Repeat("p", 70)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password+"pe"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &User{EncryptedPassword: string(hash)}
+	if !user.ValidPassword(password) {
+		t.Errorf("ValidPassword of a password truncated with the pepper = false")
+	}
+
+	long := strings.Repeat("é", 50)
+	if err := user.SetPassword(long); err != nil {
+		t.Fatalf("SetPassword of %d bytes err: %v", len(long), err)
+	}
+	if !user.ValidPassword(long) || !user.ValidPassword(long[:72]+"ignored") {
+		t.Errorf("ValidPassword of the long password = false")
+	}
+	if user.ValidPassword(long[:70]) {
+		t.Errorf("ValidPassword of a shorter password = true")
+	}
+}
+
+func TestRememberMe(t *testing.T) {
+	defer func(d time.Duration) { Devise.RememberFor = d }(Devise.RememberFor)
+	Devise.RememberFor = 14 * 24 * time.Hour
+
+	// the salt of the remember cookie of session.signedRememberCookie
+	token := "$2a$11$hGNO577ObqIlHtD/cMMRH."
+	hash := token + "Qz1dvb6WpGpKtPVL1lf3uQjUqEdT1Ni"
+	now := time.Now()
+	tests := []struct {
+		name        string
+		createdAt   time.Time
+		token       string
+		generatedAt time.Time
+		want        bool
+	}{
+		{"valid", now.Add(-2 * time.Hour), token, now.Add(-time.Hour), true},
+		{"no remember_created_at", time.Time{}, token, now.Add(-time.Hour), false},
+		{"older than remember_for", now.Add(-20 * 24 * time.Hour), token, now.Add(-15 * 24 * time.Hour), false},
+		{"older than remember_created_at", now.Add(-time.Hour), token, now.Add(-2 * time.Hour), false},
+		{"other salt", now.Add(-2 * time.Hour), "$2a$11$hGNO577ObqIlHtD/cMMRH/", now.Add(-time.Hour), false},
+		{"no token", now.Add(-2 * time.Hour), "", now.Add(-time.Hour), false},
+		{"no generated_at", now.Add(-2 * time.Hour), token, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		user := &User{EncryptedPassword: hash, RememberCreatedAt: tt.createdAt}
+		if got := user.RememberMe(tt.token, tt.generatedAt); got != tt.want {
+			t.Errorf("%s: RememberMe = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// testStore returns a store on an in-memory SQLite database with the users
+// table of db/schema.rb.
+func testStore(t *testing.T) *Store {
+	s, err := OpenStore("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each connection would have its own database
+	s.DB().SetMaxOpenConns(1)
+	_, err = s.DB().Exec(`CREATE TABLE users (
+		id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+		email varchar DEFAULT '' NOT NULL,
+		encrypted_password varchar DEFAULT '' NOT NULL,
+		reset_password_token varchar,
+		reset_password_sent_at datetime,
+		remember_created_at datetime,
+		sign_in_count integer DEFAULT 0 NOT NULL,
+		current_sign_in_at datetime,
+		last_sign_in_at datetime,
+		current_sign_in_ip varchar,
+		last_sign_in_ip varchar,
+		created_at datetime NOT NULL,
+		updated_at datetime NOT NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTrackSignIn(t *testing.T) {
+	s := testStore(t)
+	defer s.DB().Close()
+	id, err := s.InsertUser(&User{Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ips []interface{}
+	s.DB().Select(&ips, "SELECT current_sign_in_ip FROM users")
+	if len(ips) != 1 || ips[0] != nil {
+		t.Errorf("InsertUser stored the IP %v, want NULL", ips)
+	}
+
+	user := &User{Id: id}
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.TrackSignIn(user, "", first); err != nil {
+		t.Fatal(err)
+	}
+	if user.SignInCount != 1 || user.CurrentSignInIp != "" || !user.CurrentSignInAt.Equal(first) {
+		t.Errorf("first TrackSignIn = %+v", user)
+	}
+	s.DB().Select(&ips, "SELECT current_sign_in_ip FROM users")
+	if len(ips) != 1 || ips[0] != nil {
+		t.Errorf("TrackSignIn without an IP stored %v, want NULL", ips)
+	}
+
+	second := first.Add(time.Hour)
+	if err := s.TrackSignIn(user, "203.0.113.7", second); err != nil {
+		t.Fatal(err)
+	}
+	// without a previous IP the last one is the new one, as in Devise
+	if user.SignInCount != 2 || user.CurrentSignInIp != "203.0.113.7" || user.LastSignInIp != "203.0.113.7" || !user.LastSignInAt.Equal(first) {
+		t.Errorf("second TrackSignIn = %+v", user)
+	}
+	if err := s.TrackSignIn(user, "198.51.100.1", second.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if user.SignInCount != 3 || user.CurrentSignInIp != "198.51.100.1" || user.LastSignInIp != "203.0.113.7" || !user.LastSignInAt.Equal(second) {
+		t.Errorf("third TrackSignIn = %+v", user)
+	}
+}
+
+func TestUserAttributesNullIP(t *testing.T) {
+	s := testStore(t)
+	defer s.DB().Close()
+	id, err := s.CreateUser(map[string]interface{}{"email": "user@example.com", "current_sign_in_ip": "", "last_sign_in_ip": "203.0.113.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ips []struct {
+		Current *string `db:"current_sign_in_ip"`
+		Last    *string `db:"last_sign_in_ip"`
+	}
+	s.DB().Select(&ips, "SELECT current_sign_in_ip, last_sign_in_ip FROM users")
+	if len(ips) != 1 || ips[0].Current != nil || ips[0].Last == nil || *ips[0].Last != "203.0.113.7" {
+		t.Errorf("CreateUser stored the IPs %+v, want NULL and 203.0.113.7", ips)
+	}
+
+	if err := s.UpdateUser(id, map[string]interface{}{"last_sign_in_ip": ""}); err != nil {
+		t.Fatal(err)
+	}
+	s.DB().Select(&ips, "SELECT current_sign_in_ip, last_sign_in_ip FROM users")
+	if len(ips) != 1 || ips[0].Last != nil {
+		t.Errorf("UpdateUser stored the IP %v, want NULL", ips[0].Last)
+	}
+}
+
+func TestUserPageStore(t *testing.T) {
+	s := testStore(t)
+	defer s.DB().Close()
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if _, err := s.InsertUser(&User{Email: email}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the default store has no database in the tests
+	page := &UserPage{Store: s, Order: map[string]string{"id": "asc"}, PerPage: 2}
+	users, err := page.Current()
+	if err != nil || len(users) != 2 || users[0].Email != "a@example.com" {
+		t.Fatalf("Current = %v, %v", users, err)
+	}
+	if page.TotalItems != 3 || page.TotalPages != 2 {
+		t.Errorf("page count = %d items, %d pages", page.TotalItems, page.TotalPages)
+	}
+	users, err = page.Next()
+	if err != nil || len(users) != 1 || users[0].Email != "c@example.com" {
+		t.Fatalf("Next = %v, %v", users, err)
+	}
+	users, err = page.Previous()
+	if err != nil || len(users) != 2 || users[1].Email != "b@example.com" {
+		t.Fatalf("Previous = %v, %v", users, err)
+	}
+}
+
+func TestDialectIP(t *testing.T) {
+	if got := dialects["postgres"].ip("users.current_sign_in_ip"); got != "split_part(users.current_sign_in_ip::text, '/', 1)" {
+		t.Errorf("postgres ip = %s", got)
+	}
+	if got := dialects["mysql"].ip("current_sign_in_ip"); got != "current_sign_in_ip" {
+		t.Errorf("mysql ip = %s", got)
+	}
+}
+
+func TestSQLiteTimestamps(t *testing.T) {
+	s := testStore(t)
+	defer s.Close()
+	_, err := s.DB().Exec(`CREATE TABLE posts (
+		id integer PRIMARY KEY,
+		published datetime(6),
+		archived_on date,
+		edited_at varchar,
+		deleted_at datetime)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DB().Exec(`INSERT INTO posts VALUES (1, '2023-11-14 22:13:20.123456', '2023-12-01', '2023-11-14 22:13:20', NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	var post struct {
+		ID         int64
+		Published  time.Time
+		ArchivedOn time.Time `db:"archived_on"`
+		EditedAt   string    `db:"edited_at"`
+		DeletedAt  time.Time `db:"deleted_at"`
+	}
+	if err := s.DB().Get(&post, `SELECT * FROM posts`); err != nil {
+		t.Fatal(err)
+	}
+	// the declared types count, not the names
+	if want := time.Date(2023, 11, 14, 22, 13, 20, 123456000, time.UTC); !post.Published.Equal(want) {
+		t.Errorf("published = %v, want %v", post.Published, want)
+	}
+	if want := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC); !post.ArchivedOn.Equal(want) {
+		t.Errorf("archived_on = %v, want %v", post.ArchivedOn, want)
+	}
+	if post.EditedAt != "2023-11-14 22:13:20" {
+		t.Errorf("edited_at = %q, want the text", post.EditedAt)
+	}
+	if !post.DeletedAt.IsZero() {
+		t.Errorf("deleted_at = %v, want the zero time", post.DeletedAt)
+	}
+}
+
+func TestSQLiteTimeType(t *testing.T) {
+	for decl, want := range map[string]bool{
+		"datetime": true, "DATETIME": true, "datetime(6)": true, "timestamp": true, "date": true,
+		"varchar": false, "text": false, "": false, "datetimes": false,
+	} {
+		if got := sqliteTimeType(decl); got != want {
+			t.Errorf("sqliteTimeType(%q) = %v, want %v", decl, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"./config"
+	c "./controllers"
+)
+
+// serve runs the server until SIGTERM or SIGINT, then fails the readiness probe
+// for the drain delay, stops accepting connections, drains the requests in
+// flight up to the shutdown timeout and closes the database.
+func serve(addr string, handler http.Handler, opts config.Server) error {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+		// the headers have to arrive within the read timeout too, against slowloris clients
+		ReadHeaderTimeout: opts.ReadTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	log.Printf("Listening and serving HTTP on %s", addr)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-signals:
+		log.Printf("%v received, draining the requests", sig)
+	}
+
+	// the load balancer needs a few health checks to see /ready fail, the
+	// server keeps serving meanwhile, a second signal stops waiting
+	c.Drain()
+	if opts.DrainDelay > 0 {
+		select {
+		case <-time.After(opts.DrainDelay):
+		case sig := <-signals:
+			log.Printf("%v received, shutting down now", sig)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		log.Printf("shutdown err: %v", err)
+	}
+	if e := c.Close(); e != nil {
+		log.Printf("close err: %v", e)
+	}
+	return err
+}
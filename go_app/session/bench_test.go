@@ -0,0 +1,50 @@
+package session
+
+import (
+	"testing"
+)
+
+// forgetDerivedKeys empties the cache of the derived keys, as if PBKDF2 ran on
+// each request like before the keys were memoized.
+func forgetDerivedKeys() {
+	derivedKeys.Lock()
+	derivedKeys.m = map[derivedKeyID][]byte{}
+	derivedKeys.Unlock()
+}
+
+func benchmarkDecrypt(b *testing.B, cookie string, fresh bool) {
+	key := Key{SecretKeyBase: testSecret}
+	if _, err := Decrypt(cookie, key); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if fresh {
+			forgetDerivedKeys()
+		}
+		if _, err := Decrypt(cookie, key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptGCM(b *testing.B)         { benchmarkDecrypt(b, gcmCookie, false) }
+func BenchmarkDecryptGCMFreshKey(b *testing.B) { benchmarkDecrypt(b, gcmCookie, true) }
+func BenchmarkDecryptCBC(b *testing.B)         { benchmarkDecrypt(b, cbcCookie, false) }
+func BenchmarkDecryptCBCFreshKey(b *testing.B) { benchmarkDecrypt(b, cbcCookie, true) }
+
+func BenchmarkKeyringDecryptCached(b *testing.B) {
+	kr := NewKeyring(Key{SecretKeyBase: testSecret})
+	kr.Cache = NewCache(1000, 0)
+	if _, _, err := kr.Decrypt(gcmCookie); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := kr.Decrypt(gcmCookie); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
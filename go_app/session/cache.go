@@ -0,0 +1,96 @@
+package session
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Cache keeps the data of the last decrypted cookies, so a client sending the
+// same cookie again isn't decrypted again. It's an LRU of a bounded size keyed
+// by the SHA256 of the cookie, the entries expire after their TTL.
+//
+// Only the decryption is cached, the metadata of the data (its expiry and
+// purpose) is still checked on each read.
+type Cache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type cacheEntry struct {
+	digest  [sha256.Size]byte
+	data    []byte
+	match   Match
+	expires time.Time
+}
+
+// NewCache returns a cache of at most size cookies kept for ttl, 0 to keep
+// them until they're evicted.
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element, size),
+	}
+}
+
+// Get returns the data and the matching key of a cookie decrypted before.
+func (c *Cache) Get(cookie string) ([]byte, *Match, bool) {
+	digest := sha256.Sum256([]byte(cookie))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[digest]
+	if !ok {
+		return nil, nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if c.ttl > 0 && !now().Before(e.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, digest)
+		return nil, nil, false
+	}
+	c.lru.MoveToFront(el)
+	// the callers own the data they get, as with a decryption
+	data := append([]byte(nil), e.data...)
+	match := e.match
+	return data, &match, true
+}
+
+// Add stores the data a cookie was decrypted to, evicting the least recently
+// used cookie when the cache is full.
+func (c *Cache) Add(cookie string, data []byte, match *Match) {
+	if c.size <= 0 {
+		return
+	}
+	digest := sha256.Sum256([]byte(cookie))
+	e := &cacheEntry{digest: digest, data: append([]byte(nil), data...), match: *match}
+	if c.ttl > 0 {
+		e.expires = now().Add(c.ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[digest]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[digest] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).digest)
+	}
+}
+
+// Len returns the number of cookies in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
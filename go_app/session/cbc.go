@@ -0,0 +1,69 @@
+package session
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// decryptCBC verifies the HMAC-SHA1 digest of a cookie written by
+// ActiveSupport::MessageEncryptor with aes-256-cbc and decrypts it.
+func decryptCBC(cookie string, secret, signSecret []byte) ([]byte, error) {
+	parts := strings.SplitN(cookie, "--", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCookie
+	}
+	digest, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	mac := hmac.New(sha1.New, signSecret)
+	mac.Write([]byte(parts[0]))
+	if !hmac.Equal(mac.Sum(nil), digest) {
+		return nil, ErrInvalidSignature
+	}
+
+	inner, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	vectors := strings.SplitN(string(inner), "--", 2)
+	if len(vectors) != 2 {
+		return nil, ErrInvalidCookie
+	}
+	data, err := base64.StdEncoding.DecodeString(vectors[0])
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	iv, err := base64.StdEncoding.DecodeString(vectors[1])
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, ErrInvalidCookie
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrInvalidCookie
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	return pkcs7Unpad(plain)
+}
+
+func pkcs7Unpad(data []byte) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, ErrInvalidCookie
+	}
+	if !bytes.Equal(data[len(data)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, ErrInvalidCookie
+	}
+	return data[:len(data)-n], nil
+}
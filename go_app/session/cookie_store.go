@@ -0,0 +1,167 @@
+package session
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// MaxCookieSize is the size limit of a cookie, Rails raises CookieOverflow above it.
+const MaxCookieSize = 4096
+
+// ErrCookieOverflow is returned when an encrypted session doesn't fit in a cookie.
+var ErrCookieOverflow = errors.New("session: cookie overflow")
+
+// CookieOptions are the attributes of the session cookie,
+// the options of Rails' `config.session_store :cookie_store`.
+type CookieOptions struct {
+	Name     string
+	Path     string
+	Domain   string
+	Secure   bool
+	HTTPOnly bool
+	SameSite http.SameSite
+	// AllDomains is Rails' `domain: :all`, the cookie is set for the domain
+	// of the request host and all its subdomains.
+	AllDomains bool
+	// ExpireAfter makes the cookie and its envelope expire, 0 for a browser session cookie.
+	ExpireAfter time.Duration
+}
+
+// CookieStore reads and writes sessions stored in the cookie itself,
+// the default session store of Rails.
+type CookieStore struct {
+	Keyring    *Keyring
+	Serializer Serializer
+	Options    CookieOptions
+	// Metadata wraps written sessions in the `_rails` envelope, as Rails 6+ does
+	// with `use_cookies_with_metadata`. Sessions are read either way.
+	Metadata bool
+}
+
+// Decode verifies and decrypts a session cookie value and returns the session
+// data as JSON, along with the key that matched and the envelope metadata, if any.
+func (cs *CookieStore) Decode(value string) ([]byte, *Match, *Metadata, error) {
+	data, match, err := cs.Keyring.Decrypt(value)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, md, err := Unwrap(data, Purpose(cs.Options.Name))
+	if err != nil {
+		return nil, match, md, err
+	}
+	data, err = Deserialize(data, cs.Serializer)
+	if err != nil {
+		return nil, match, md, err
+	}
+	return data, match, md, nil
+}
+
+// Encode is the inverse of Decode, it serializes, wraps and encrypts JSON
+// session data with the current key and returns the cookie value.
+func (cs *CookieStore) Encode(data []byte) (string, error) {
+	data, err := Serialize(data, cs.Serializer)
+	if err != nil {
+		return "", err
+	}
+	if cs.Metadata {
+		var expires time.Time
+		if cs.Options.ExpireAfter > 0 {
+			expires = now().Add(cs.Options.ExpireAfter)
+		}
+		if data, err = Wrap(data, Purpose(cs.Options.Name), expires); err != nil {
+			return "", err
+		}
+	}
+	value, err := cs.Keyring.Encrypt(data)
+	if err != nil {
+		return "", err
+	}
+	if len(cs.Options.Name)+len(value)+1 > MaxCookieSize {
+		return "", ErrCookieOverflow
+	}
+	return value, nil
+}
+
+// Load reads the session of a request, a request without a session cookie gets
+// a new empty session. Errors of an existing cookie are returned as is.
+func (cs *CookieStore) Load(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(cs.Options.Name)
+	if err == http.ErrNoCookie {
+		return New(nil), nil
+	}
+	data, _, _, err := cs.Decode(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	s.exists = true
+	return s, nil
+}
+
+// Save writes the session to the response with a Set-Cookie header.
+// It must be called before the response body is written.
+func (cs *CookieStore) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	if _, ok := s.values["session_id"]; !ok {
+		s.values["session_id"] = NewSessionID()
+	}
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	value, err := cs.Encode(data)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, cs.Cookie(r, value))
+	return nil
+}
+
+// Cookie returns the session cookie of the request with the value and the store's attributes.
+func (cs *CookieStore) Cookie(r *http.Request, value string) *http.Cookie {
+	return cs.Options.cookie(r, value)
+}
+
+func (opts CookieOptions) cookie(r *http.Request, value string) *http.Cookie {
+	c := &http.Cookie{
+		Name:     opts.Name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HTTPOnly,
+		SameSite: opts.SameSite,
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if opts.AllDomains {
+		c.Domain = allDomains(r.Host)
+	}
+	if opts.ExpireAfter > 0 {
+		c.Expires = now().Add(opts.ExpireAfter)
+	}
+	return c
+}
+
+var topLevelDomain = regexp.MustCompile(`[^.]*\.([^.]*|..\...|...\...)$`)
+
+// allDomains returns the domain of `domain: :all` for a host, like Rails:
+// ".example.com" for "www.example.com", nothing for an IP or a bare name.
+func allDomains(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	if d := topLevelDomain.FindString(host); d != "" {
+		return "." + d
+	}
+	return ""
+}
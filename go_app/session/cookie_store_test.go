@@ -0,0 +1,116 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCookieStore() *CookieStore {
+	return &CookieStore{
+		Keyring:    NewKeyring(Key{SecretKeyBase: testSecret}),
+		Serializer: JSONSerializer,
+		Options: CookieOptions{
+			Name:        testCookieName,
+			HTTPOnly:    true,
+			Secure:      true,
+			SameSite:    http.SameSiteLaxMode,
+			ExpireAfter: time.Hour,
+		},
+		Metadata: true,
+	}
+}
+
+func TestCookieStoreLoad(t *testing.T) {
+	cs := testCookieStore()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: testCookieName, Value: gcmCookie})
+	s, err := cs.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Exists() || s.Get("session_id") != "a3b3b33fc3336a5e29c99bbc09db714d" {
+		t.Errorf("Load = %+v", s.Values())
+	}
+
+	s, err = cs.Load(httptest.NewRequest("GET", "/", nil))
+	if err != nil || s.Exists() || len(s.Values()) != 0 {
+		t.Errorf("Load without a cookie = %+v, %v", s, err)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: testCookieName, Value: tamper(gcmCookie, 0)})
+	if _, err := cs.Load(r); err != ErrInvalidSignature {
+		t.Errorf("Load of a tampered cookie err = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestCookieStoreSave(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	cs := testCookieStore()
+	s := New(nil)
+	s.Set("user_return_to", "/orders")
+	w := httptest.NewRecorder()
+	if err := cs.Save(w, httptest.NewRequest("GET", "/", nil), s); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Save set %d cookies, want 1", len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != testCookieName || c.Path != "/" || !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteLaxMode || !c.Expires.Equal(now().Add(time.Hour)) {
+		t.Errorf("Save cookie = %+v", c)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(c)
+	loaded, err := cs.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Get("user_return_to") != "/orders" || loaded.Get("session_id") == nil {
+		t.Errorf("Load(Save) = %+v", loaded.Values())
+	}
+
+	// the envelope expires with the cookie
+	now = func() time.Time { return time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC) }
+	if _, err := cs.Load(r); err != ErrExpired {
+		t.Errorf("Load of an expired session err = %v, want %v", err, ErrExpired)
+	}
+}
+
+func TestCookieStoreOverflow(t *testing.T) {
+	cs := testCookieStore()
+	s := New(nil)
+	s.Set("big", strings.Repeat("x", MaxCookieSize))
+	w := httptest.NewRecorder()
+	if err := cs.Save(w, httptest.NewRequest("GET", "/", nil), s); err != ErrCookieOverflow {
+		t.Errorf("Save err = %v, want %v", err, ErrCookieOverflow)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("Save of an overflowing session set a cookie")
+	}
+}
+
+func TestAllDomains(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"www.example.com", ".example.com"},
+		{"example.com:3000", ".example.com"},
+		{"shop.example.co.uk", ".example.co.uk"},
+		{"127.0.0.1:3000", ""},
+		{"localhost", ""},
+	}
+	for _, tt := range tests {
+		if got := allDomains(tt.host); got != tt.want {
+			t.Errorf("allDomains(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
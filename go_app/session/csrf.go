@@ -0,0 +1,122 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
+// The CSRF tokens of ActionController::RequestForgeryProtection: the real
+// token is kept in the session and the pages only get masked tokens, a random
+// one-time pad followed by the token XORed with it, so they differ on each page.
+
+// CSRFTokenKey is the session key of the real CSRF token.
+const CSRFTokenKey = "_csrf_token"
+
+const (
+	csrfTokenLength = 32
+	// the identifier of the global token, masked instead of the real one since Rails 6.0.3.1
+	globalCSRFTokenIdent = "!real_csrf_token"
+)
+
+// RealCSRFToken returns the real CSRF token of the session, a new one is
+// stored in the session when there's none yet.
+func (s *Session) RealCSRFToken(urlSafe bool) ([]byte, error) {
+	if v, ok := s.values[CSRFTokenKey].(string); ok {
+		if token, err := decodeCSRFToken(v); err == nil && len(token) == csrfTokenLength {
+			return token, nil
+		}
+	}
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	s.Set(CSRFTokenKey, encodeCSRFToken(token, urlSafe))
+	return token, nil
+}
+
+// MaskedCSRFToken returns a masked token of the session for a page, like
+// form_authenticity_token. Without an action the token is valid for any form,
+// otherwise only for the form of the action path and the method.
+func (s *Session) MaskedCSRFToken(action, method string, urlSafe bool) (string, error) {
+	real, err := s.RealCSRFToken(urlSafe)
+	if err != nil {
+		return "", err
+	}
+	raw := csrfTokenHMAC(real, globalCSRFTokenIdent)
+	if action != "" && method != "" {
+		raw = perFormCSRFToken(real, action, method)
+	}
+	pad := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(pad); err != nil {
+		return "", err
+	}
+	return encodeCSRFToken(append(pad, xorBytes(pad, raw)...), urlSafe), nil
+}
+
+// ValidCSRFToken checks a token sent with a request to the path with the
+// method, like valid_authenticity_token?. Masked tokens of the real token, of
+// the global token and, with perForm, of the per-form token of the path are
+// accepted, as well as the unmasked real token of older Rails versions.
+func (s *Session) ValidCSRFToken(token, path, method string, perForm bool) bool {
+	v, ok := s.values[CSRFTokenKey].(string)
+	if !ok || token == "" {
+		return false
+	}
+	real, err := decodeCSRFToken(v)
+	if err != nil || len(real) != csrfTokenLength {
+		return false
+	}
+	masked, err := decodeCSRFToken(token)
+	if err != nil {
+		return false
+	}
+	switch len(masked) {
+	case csrfTokenLength:
+		return subtle.ConstantTimeCompare(masked, real) == 1
+	case 2 * csrfTokenLength:
+		unmasked := xorBytes(masked[:csrfTokenLength], masked[csrfTokenLength:])
+		return subtle.ConstantTimeCompare(unmasked, csrfTokenHMAC(real, globalCSRFTokenIdent)) == 1 ||
+			subtle.ConstantTimeCompare(unmasked, real) == 1 ||
+			perForm && subtle.ConstantTimeCompare(unmasked, perFormCSRFToken(real, strings.TrimSuffix(path, "/"), method)) == 1
+	}
+	return false
+}
+
+func perFormCSRFToken(real []byte, action, method string) []byte {
+	return csrfTokenHMAC(real, action+"#"+strings.ToLower(method))
+}
+
+func csrfTokenHMAC(real []byte, identifier string) []byte {
+	mac := hmac.New(sha256.New, real)
+	mac.Write([]byte(identifier))
+	return mac.Sum(nil)
+}
+
+func xorBytes(a, b []byte) []byte {
+	res := make([]byte, len(a))
+	for i := range a {
+		res[i] = a[i] ^ b[i]
+	}
+	return res
+}
+
+// decodeCSRFToken decodes both the strict and the url safe base64 tokens,
+// like Ruby's Base64.urlsafe_decode64.
+func decodeCSRFToken(token string) ([]byte, error) {
+	token = strings.NewReplacer("-", "+", "_", "/").Replace(token)
+	if n := len(token) % 4; n != 0 {
+		token += strings.Repeat("=", 4-n)
+	}
+	return base64.StdEncoding.DecodeString(token)
+}
+
+func encodeCSRFToken(token []byte, urlSafe bool) string {
+	if urlSafe {
+		return base64.RawURLEncoding.EncodeToString(token)
+	}
+	return base64.StdEncoding.EncodeToString(token)
+}
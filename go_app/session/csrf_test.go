@@ -0,0 +1,95 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+// Masked tokens of the _csrf_token of testSessionJSON, as Rails 6.0.3.1+ writes
+// them with the one-time pad 100, 101, ..., 131: of the global token, of the
+// real token (Rails < 6.0.3.1) and of the form of POST /users/sign_in.
+const (
+	maskedGlobalToken = "ZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXp7fH1+f4CBgoM0jNAAZDsbpuzwZHD+0AnSjdNweEgE8EOimrASe+Ge5A=="
+	maskedRealToken   = "ZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXp7fH1+f4CBgoMmy1aNtz+IXmTHrmVX0Vbf8cHW4vTvKUsXaVX+tFhRbA=="
+	maskedFormToken   = "ZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXp7fH1+f4CBgoNTlnWh+aMyNvq9xYT0ULfQ+OniMd6tW8vrvumlTnftNw=="
+	urlSafeFormToken  = "ZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXp7fH1-f4CBgoNTlnWh-aMyNvq9xYT0ULfQ-OniMd6tW8vrvumlTnftNw"
+	testRealCSRFToken = "Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8="
+	testSignInPath    = "/users/sign_in"
+	testSignInMethod  = "POST"
+	otherSessionToken = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+)
+
+func TestValidCSRFToken(t *testing.T) {
+	s := New(map[string]interface{}{CSRFTokenKey: testRealCSRFToken})
+	tests := []struct {
+		name    string
+		token   string
+		path    string
+		method  string
+		perForm bool
+		want    bool
+	}{
+		{"global", maskedGlobalToken, "/orders", "POST", true, true},
+		{"masked real", maskedRealToken, "/orders", "POST", false, true},
+		{"unmasked real", testRealCSRFToken, "/orders", "POST", false, true},
+		{"per form", maskedFormToken, testSignInPath, testSignInMethod, true, true},
+		{"per form trailing slash", maskedFormToken, testSignInPath + "/", "post", true, true},
+		{"per form url safe", urlSafeFormToken, testSignInPath, testSignInMethod, true, true},
+		{"per form disabled", maskedFormToken, testSignInPath, testSignInMethod, false, false},
+		{"per form other path", maskedFormToken, "/users/password", testSignInMethod, true, false},
+		{"per form other method", maskedFormToken, testSignInPath, "DELETE", true, false},
+		{"tampered", tamper(maskedGlobalToken, 60), "/orders", "POST", true, false},
+		{"truncated", maskedGlobalToken[:60], "/orders", "POST", true, false},
+		{"other session", otherSessionToken, "/orders", "POST", true, false},
+		{"not base64", "!!!", "/orders", "POST", true, false},
+		{"empty", "", "/orders", "POST", true, false},
+	}
+	for _, tt := range tests {
+		if got := s.ValidCSRFToken(tt.token, tt.path, tt.method, tt.perForm); got != tt.want {
+			t.Errorf("%s: ValidCSRFToken = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if New(nil).ValidCSRFToken(maskedGlobalToken, "/orders", "POST", true) {
+		t.Errorf("ValidCSRFToken without a session token = true")
+	}
+}
+
+func TestMaskedCSRFToken(t *testing.T) {
+	for _, urlSafe := range []bool{false, true} {
+		s := New(map[string]interface{}{CSRFTokenKey: testRealCSRFToken})
+		global, err := s.MaskedCSRFToken("", "", urlSafe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, _ := s.MaskedCSRFToken("", "", urlSafe)
+		if global == again {
+			t.Errorf("MaskedCSRFToken reused the pad")
+		}
+		if urlSafe == strings.ContainsAny(global, "+/=") {
+			t.Errorf("MaskedCSRFToken(urlSafe %v) = %s", urlSafe, global)
+		}
+		form, _ := s.MaskedCSRFToken(testSignInPath, testSignInMethod, urlSafe)
+		if !s.ValidCSRFToken(global, "/orders", "POST", false) || !s.ValidCSRFToken(form, testSignInPath, testSignInMethod, true) {
+			t.Errorf("the masked tokens of the session aren't valid: %s, %s", global, form)
+		}
+		if s.ValidCSRFToken(form, "/orders", "POST", true) {
+			t.Errorf("the per form token is valid for another form")
+		}
+		if s.Get(CSRFTokenKey) != testRealCSRFToken {
+			t.Errorf("MaskedCSRFToken replaced the real token")
+		}
+	}
+
+	s := New(nil)
+	token, err := s.MaskedCSRFToken("", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if real, _ := s.Get(CSRFTokenKey).(string); len(real) != 44 || !s.Changed() {
+		t.Errorf("MaskedCSRFToken stored the real token %q", real)
+	}
+	if !s.ValidCSRFToken(token, "/", "POST", false) {
+		t.Errorf("the token of a new session isn't valid")
+	}
+}
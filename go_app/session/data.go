@@ -0,0 +1,101 @@
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Session is the data of a Rails session. Changes are tracked,
+// so only sessions that have been changed need to be written back.
+type Session struct {
+	values  map[string]interface{}
+	changed bool
+	exists  bool
+	// flash is loaded from the values on first use, see Flash
+	flash *Flash
+	// loadedID is the id a ServerStore loaded the session with
+	loadedID string
+}
+
+// New returns a session with the values, e.g. the ones of a decoded cookie.
+func New(values map[string]interface{}) *Session {
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return &Session{values: values}
+}
+
+// Parse returns the session of JSON session data.
+// Numbers are kept as json.Number so integers and floats survive a round trip.
+func Parse(data []byte) (*Session, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values map[string]interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	return New(values), nil
+}
+
+// Get returns the value of a key, or nil.
+func (s *Session) Get(key string) interface{} {
+	return s.values[key]
+}
+
+// Set sets the value of a key, like `session[key] = value` in Rails.
+func (s *Session) Set(key string, value interface{}) {
+	s.values[key] = value
+	s.changed = true
+}
+
+// Delete removes a key, like `session.delete(key)` in Rails.
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+}
+
+// Clear removes all the keys and assigns a new session id,
+// like `reset_session` in Rails, e.g. to sign a user out.
+func (s *Session) Clear() {
+	s.values = map[string]interface{}{"session_id": NewSessionID()}
+	s.changed = true
+	s.flash = nil
+}
+
+// Renew assigns a new session id and keeps the data, as Warden does when
+// a user signs in to prevent session fixation.
+func (s *Session) Renew() {
+	s.Set("session_id", NewSessionID())
+}
+
+// Values returns the data of the session, it must not be modified directly.
+func (s *Session) Values() map[string]interface{} {
+	return s.values
+}
+
+// Exists reports whether the session was loaded from the request,
+// rather than created for a request without one.
+func (s *Session) Exists() bool {
+	return s.exists
+}
+
+// Changed reports whether the session has been changed since it was loaded.
+func (s *Session) Changed() bool {
+	return s.changed
+}
+
+// MarshalJSON returns the JSON session data.
+func (s *Session) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.values)
+}
+
+// NewSessionID returns a random session id in the format of Rack.
+func NewSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
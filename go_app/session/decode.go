@@ -0,0 +1,256 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeError tells where the session data doesn't match the Go type it's
+// decoded into, e.g. `$["warden.user.user.key"][0][0]: expected int64, got string`.
+type DecodeError struct {
+	Path     string
+	Expected string
+	Got      string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("session: %s: expected %s, got %s", e.Path, e.Expected, e.Got)
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	numberType = reflect.TypeOf(json.Number(""))
+)
+
+// Decode maps the session data into a Go value, a pointer to a struct or a
+// map. The keys of the struct fields are taken from their `session` tag, then
+// their `json` tag, then their name, e.g.
+//
+//	var s struct {
+//		UserKey []interface{} `session:"warden.user.user.key"`
+//		Flash   struct {
+//			Flashes map[string]string `session:"flashes"`
+//		} `session:"flash"`
+//	}
+//
+// Keys without a field are ignored and missing keys leave the fields as is. A
+// value of another shape than its field stops the decoding with a DecodeError.
+func (s *Session) Decode(into interface{}) error {
+	v := reflect.ValueOf(into)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("session: Decode needs a non-nil pointer, got %T", into)
+	}
+	return decodeValue("$", s.values, v.Elem())
+}
+
+func decodeValue(path string, src interface{}, dst reflect.Value) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decodeValue(path, src, dst.Elem())
+	}
+	mismatch := func() error {
+		return &DecodeError{Path: path, Expected: dst.Type().String(), Got: kindOf(src)}
+	}
+
+	switch {
+	case dst.Type() == timeType:
+		str, ok := src.(string)
+		if !ok {
+			return mismatch()
+		}
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return &DecodeError{Path: path, Expected: "RFC 3339 time", Got: strconv.Quote(str)}
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	case dst.Type() == numberType:
+		n, ok := number(src)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetString(n)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return mismatch()
+		}
+		dst.Set(reflect.ValueOf(src))
+	case reflect.String:
+		str, ok := src.(string)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetString(str)
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := number(src)
+		if !ok {
+			return mismatch()
+		}
+		i, err := strconv.ParseInt(n, 10, 64)
+		if err != nil || dst.OverflowInt(i) {
+			return &DecodeError{Path: path, Expected: dst.Type().String(), Got: "number " + n}
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := number(src)
+		if !ok {
+			return mismatch()
+		}
+		u, err := strconv.ParseUint(n, 10, 64)
+		if err != nil || dst.OverflowUint(u) {
+			return &DecodeError{Path: path, Expected: dst.Type().String(), Got: "number " + n}
+		}
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		n, ok := number(src)
+		if !ok {
+			return mismatch()
+		}
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil || dst.OverflowFloat(f) {
+			return &DecodeError{Path: path, Expected: dst.Type().String(), Got: "number " + n}
+		}
+		dst.SetFloat(f)
+	case reflect.Slice:
+		items, ok := src.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(fmt.Sprintf("%s[%d]", path, i), item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	case reflect.Array:
+		items, ok := src.([]interface{})
+		if !ok || len(items) != dst.Len() {
+			return mismatch()
+		}
+		for i, item := range items {
+			if err := decodeValue(fmt.Sprintf("%s[%d]", path, i), item, dst.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		obj, ok := src.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), len(obj))
+		for k, item := range obj {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := decodeValue(childPath(path, k), item, elem); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+		dst.Set(m)
+	case reflect.Struct:
+		obj, ok := src.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			key := fieldKey(f)
+			if key == "" {
+				continue
+			}
+			item, ok := obj[key]
+			if !ok {
+				continue
+			}
+			if err := decodeValue(childPath(path, key), item, dst.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// fieldKey returns the session key of a struct field, "" to skip it.
+func fieldKey(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	for _, tag := range []string{"session", "json"} {
+		if name := strings.Split(f.Tag.Get(tag), ",")[0]; name == "-" {
+			return ""
+		} else if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func childPath(path, key string) string {
+	if identifier.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+// number returns the decimal representation of a number of the session data.
+func number(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.String(), true
+	case int64:
+		return strconv.FormatInt(n, 10), true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return strconv.FormatInt(int64(n), 10), true
+		}
+		return strconv.FormatFloat(n, 'g', -1, 64), true
+	case *big.Int:
+		return n.String(), true
+	}
+	return "", false
+}
+
+// kindOf names the JSON type of a value of the session data.
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number, int64, float64, *big.Int:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
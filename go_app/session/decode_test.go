@@ -0,0 +1,152 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	s, err := Parse([]byte(testSessionJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		SessionID string         `session:"session_id"`
+		UserKey   [2]interface{} `session:"warden.user.user.key"`
+		Flash     *struct {
+			Discard []string          `json:"discard"`
+			Flashes map[string]string `session:"flashes"`
+		} `session:"flash"`
+		Missing    string `session:"missing"`
+		Skipped    string `session:"-"`
+		unexported string
+	}
+	got.Missing = "kept"
+	if err := s.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SessionID != "a3b3b33fc3336a5e29c99bbc09db714d" || got.Missing != "kept" || got.Flash == nil ||
+		got.Flash.Flashes["notice"] != "Welcome! You have signed up successfully." || len(got.Flash.Discard) != 0 {
+		t.Errorf("Decode = %+v", got)
+	}
+	if got.UserKey[1] != "$2a$11$hGNO577ObqIlHtD/cMMRH." {
+		t.Errorf("Decode warden key = %v", got.UserKey)
+	}
+}
+
+func TestDecodeTypes(t *testing.T) {
+	s, err := Parse([]byte(`{"id":2,"big":18446744073709551616,"ratio":0.5,"ok":true,"at":"2023-01-15T12:30:00.000Z","ids":[1,2],"n":null,"tags":{"a":"x"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		ID    int32
+		Big   json.Number `session:"big"`
+		Ratio float64     `session:"ratio"`
+		OK    bool        `json:"ok"`
+		At    time.Time   `session:"at"`
+		IDs   []uint8     `session:"ids"`
+		N     *string     `session:"n"`
+		Tags  map[string]string
+	}
+	// the field names are case sensitive, like the session keys
+	got.ID = -1
+	if err := s.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2023, 1, 15, 12, 30, 0, 0, time.UTC)
+	if got.ID != -1 || got.Big != "18446744073709551616" || got.Ratio != 0.5 || !got.OK || !got.At.Equal(want) ||
+		!reflect.DeepEqual(got.IDs, []uint8{1, 2}) || got.N != nil || got.Tags != nil {
+		t.Errorf("Decode = %+v", got)
+	}
+
+	// the numbers of the Marshal decoder
+	m := New(map[string]interface{}{"id": int64(7), "big": bigInt("18446744073709551616"), "ratio": 2.0})
+	var typed struct {
+		ID    int64   `session:"id"`
+		Big   string  `session:"big"`
+		Ratio float32 `session:"ratio"`
+	}
+	if err := m.Decode(&typed); err == nil {
+		t.Errorf("Decode of a bignum into a string err = nil")
+	}
+	var numbers struct {
+		ID    int64       `session:"id"`
+		Big   json.Number `session:"big"`
+		Ratio float32     `session:"ratio"`
+	}
+	if err := m.Decode(&numbers); err != nil || numbers.ID != 7 || numbers.Big != "18446744073709551616" || numbers.Ratio != 2 {
+		t.Errorf("Decode of Marshal numbers = %+v, %v", numbers, err)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	s, err := Parse([]byte(testSessionJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		into interface{}
+		want DecodeError
+	}{
+		{"string", &struct {
+			ID int64 `session:"session_id"`
+		}{}, DecodeError{"$.session_id", "int64", "string"}},
+		{"nested", &struct {
+			Key [][]string `session:"warden.user.user.key"`
+		}{}, DecodeError{`$["warden.user.user.key"][0][0]`, "string", "number"}},
+		{"array length", &struct {
+			Key [3]interface{} `session:"warden.user.user.key"`
+		}{}, DecodeError{`$["warden.user.user.key"]`, "[3]interface {}", "array"}},
+		{"object", &struct {
+			Flash []string `session:"flash"`
+		}{}, DecodeError{"$.flash", "[]string", "object"}},
+	}
+	for _, tt := range tests {
+		err := s.Decode(tt.into)
+		derr, ok := err.(*DecodeError)
+		if !ok || *derr != tt.want {
+			t.Errorf("%s: Decode err = %v, want %v", tt.name, err, &tt.want)
+		}
+	}
+	var overflow struct {
+		Count int8 `session:"count"`
+	}
+	err = New(map[string]interface{}{"count": json.Number("300")}).Decode(&overflow)
+	if derr, ok := err.(*DecodeError); !ok || *derr != (DecodeError{"$.count", "int8", "number 300"}) {
+		t.Errorf("overflow: Decode err = %v", err)
+	}
+	if err := s.Decode(struct{}{}); err == nil {
+		t.Errorf("Decode into a non-pointer err = nil")
+	}
+}
+
+func TestRailsSession(t *testing.T) {
+	s, err := Parse([]byte(testSessionJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := s.Rails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.SessionID != "a3b3b33fc3336a5e29c99bbc09db714d" || rs.CSRFToken != testRealCSRFToken ||
+		rs.Flash["notice"] != "Welcome! You have signed up successfully." {
+		t.Errorf("Rails = %+v", rs)
+	}
+	want := &WardenUser{Scope: "user", ID: 2, Salt: "$2a$11$hGNO577ObqIlHtD/cMMRH."}
+	if !reflect.DeepEqual(rs.Warden["user"], want) {
+		t.Errorf("Rails warden user = %+v, want %+v", rs.Warden["user"], want)
+	}
+
+	s.Set(WardenKey("admin"), []interface{}{"2", "salt"})
+	if _, err := s.Rails(); err == nil {
+		t.Errorf("Rails of a malformed warden user err = nil")
+	}
+	if _, err := New(nil).WardenUser("user"); err != ErrNoWardenUser {
+		t.Errorf("WardenUser without a user err = %v, want %v", err, ErrNoWardenUser)
+	}
+}
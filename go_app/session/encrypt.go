@@ -0,0 +1,95 @@
+package session
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// Encrypt is the inverse of Decrypt: it encrypts and signs serialized session
+// data with the key and returns the escaped cookie value. Keys without a
+// cipher encrypt with GCM, the default since Rails 5.2.
+func Encrypt(data []byte, key Key) (string, error) {
+	var (
+		cookie string
+		err    error
+	)
+	switch key.Cipher {
+	case CBC:
+		cookie, err = encryptCBC(data, key.deriveKey(orDefault(key.Salt, EncryptedCookieSalt), 32), key.deriveKey(orDefault(key.SignSalt, EncryptedSignedCookieSalt), 64))
+	case GCM, "":
+		cookie, err = EncryptGCM(data, key.deriveKey(orDefault(key.AuthenticatedSalt, AuthenticatedEncryptedCookieSalt), 32))
+	default:
+		return "", ErrInvalidCookie
+	}
+	if err != nil {
+		return "", err
+	}
+	return url.QueryEscape(cookie), nil
+}
+
+func encryptCBC(data, secret, signSecret []byte) (string, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	plain := pkcs7Pad(data)
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, plain)
+
+	inner := base64.StdEncoding.EncodeToString(encrypted) + "--" + base64.StdEncoding.EncodeToString(iv)
+	signed := base64.StdEncoding.EncodeToString([]byte(inner))
+	mac := hmac.New(sha1.New, signSecret)
+	mac.Write([]byte(signed))
+	return signed + "--" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// EncryptGCM is the inverse of DecryptGCM.
+func EncryptGCM(data, secret []byte) (string, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, iv, data, nil)
+	encrypted, tag := sealed[:len(sealed)-gcmTagSize], sealed[len(sealed)-gcmTagSize:]
+	return base64.StdEncoding.EncodeToString(encrypted) + "--" +
+		base64.StdEncoding.EncodeToString(iv) + "--" +
+		base64.StdEncoding.EncodeToString(tag), nil
+}
+
+func pkcs7Pad(data []byte) []byte {
+	n := aes.BlockSize - len(data)%aes.BlockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// Wrap is the inverse of Unwrap, it puts serialized session data in the
+// `_rails` envelope of Rails 6+. A zero expires writes no expiry.
+func Wrap(data []byte, purpose string, expires time.Time) ([]byte, error) {
+	msg := base64.StdEncoding.EncodeToString(data)
+	var exp *string
+	if !expires.IsZero() {
+		s := expires.UTC().Format("2006-01-02T15:04:05.000Z")
+		exp = &s
+	}
+	return json.Marshal(envelope{Rails: &envelopeFields{Message: &msg, Exp: exp, Pur: &purpose}})
+}
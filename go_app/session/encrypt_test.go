@@ -0,0 +1,44 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+	}{
+		{"gcm", Key{SecretKeyBase: testSecret}},
+		{"gcm sha256", Key{SecretKeyBase: testSecret, Digest: SHA256}},
+		{"cbc", Key{SecretKeyBase: testSecret, Cipher: CBC}},
+		{"cbc sha256", Key{SecretKeyBase: testSecret, Cipher: CBC, Digest: SHA256}},
+	}
+	for _, tt := range tests {
+		cookie, err := Encrypt([]byte(testSessionJSON), tt.key)
+		if err != nil {
+			t.Errorf("%s: Encrypt err: %v", tt.name, err)
+			continue
+		}
+		want := tt.key.Cipher
+		if want == "" {
+			want = GCM
+		}
+		if got := DetectCipher(strings.Replace(cookie, "%3D", "=", -1)); got != want {
+			t.Errorf("%s: Encrypt wrote a %q cookie, want %q", tt.name, got, want)
+		}
+		// as Rails reads it, detecting the cipher
+		data, err := Decrypt(cookie, Key{SecretKeyBase: testSecret, Digest: tt.key.Digest})
+		if err != nil || string(data) != testSessionJSON {
+			t.Errorf("%s: Decrypt(Encrypt) = %s, %v", tt.name, data, err)
+		}
+		again, _ := Encrypt([]byte(testSessionJSON), tt.key)
+		if again == cookie {
+			t.Errorf("%s: Encrypt reused the IV", tt.name)
+		}
+	}
+	if _, err := Encrypt(nil, Key{SecretKeyBase: testSecret, Cipher: "aes-128-ecb"}); err != ErrInvalidCookie {
+		t.Errorf("Encrypt with an unknown cipher err = %v, want %v", err, ErrInvalidCookie)
+	}
+}
@@ -0,0 +1,151 @@
+package session
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// FlashKey is the session key of the flash of ActionDispatch::Flash, stored as
+// {"discard": [...], "flashes": {...}} since Rails 4.1.
+const FlashKey = "flash"
+
+// Flash is the flash of a request, with the sweeping of Rails: the messages
+// set by the previous request are shown and dropped at the end of this one,
+// unless they're kept, and the messages set now are left for the next request.
+type Flash struct {
+	flashes map[string]interface{}
+	discard map[string]bool
+}
+
+// Flash returns the flash of the session, it's loaded and swept on first use
+// like in Rails, so the session is left as is for handlers that don't use it.
+func (s *Session) Flash() *Flash {
+	if s.flash == nil {
+		s.flash = loadFlash(s.values[FlashKey])
+	}
+	return s.flash
+}
+
+func loadFlash(v interface{}) *Flash {
+	f := &Flash{flashes: map[string]interface{}{}, discard: map[string]bool{}}
+	stored, _ := v.(map[string]interface{})
+	flashes, _ := stored["flashes"].(map[string]interface{})
+	for k, msg := range flashes {
+		f.flashes[k] = msg
+	}
+	// the messages a Rails 4.1 ~ 4.2 app discarded but still stored
+	discard, _ := stored["discard"].([]interface{})
+	for _, k := range discard {
+		if k, ok := k.(string); ok {
+			delete(f.flashes, k)
+		}
+	}
+	for k := range f.flashes {
+		f.discard[k] = true
+	}
+	return f
+}
+
+// CommitFlash writes the flash back to the session without the discarded
+// messages, like Rails does at the end of a request. It must be called before
+// the session is saved.
+func (s *Session) CommitFlash() {
+	if s.flash == nil {
+		return
+	}
+	if v := s.flash.sessionValue(); v != nil {
+		s.Set(FlashKey, v)
+	} else {
+		s.Delete(FlashKey)
+	}
+}
+
+func (f *Flash) sessionValue() interface{} {
+	kept := map[string]interface{}{}
+	for k, msg := range f.flashes {
+		if !f.discard[k] {
+			kept[k] = msg
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"discard": []interface{}{}, "flashes": kept}
+}
+
+// Get returns the message of a key, or nil.
+func (f *Flash) Get(key string) interface{} {
+	return f.flashes[key]
+}
+
+// Notice returns the flash[:notice] message.
+func (f *Flash) Notice() string {
+	msg, _ := f.flashes["notice"].(string)
+	return msg
+}
+
+// Alert returns the flash[:alert] message.
+func (f *Flash) Alert() string {
+	msg, _ := f.flashes["alert"].(string)
+	return msg
+}
+
+// Set sets a message for the next request, like `flash[key] = msg`,
+// e.g. before redirecting to a Rails page.
+func (f *Flash) Set(key string, msg interface{}) {
+	f.flashes[key] = msg
+	delete(f.discard, key)
+}
+
+// Now sets a message for this request only, like `flash.now[key] = msg`.
+func (f *Flash) Now(key string, msg interface{}) {
+	f.flashes[key] = msg
+	f.discard[key] = true
+}
+
+// Keep keeps the messages of the keys, or all of them, for the next request too.
+func (f *Flash) Keep(keys ...string) {
+	if len(keys) == 0 {
+		f.discard = map[string]bool{}
+		return
+	}
+	for _, k := range keys {
+		delete(f.discard, k)
+	}
+}
+
+// Discard drops the messages of the keys, or all of them, at the end of the request.
+func (f *Flash) Discard(keys ...string) {
+	if len(keys) == 0 {
+		keys = f.Keys()
+	}
+	for _, k := range keys {
+		f.discard[k] = true
+	}
+}
+
+// Delete removes a message right away.
+func (f *Flash) Delete(key string) {
+	delete(f.flashes, key)
+	delete(f.discard, key)
+}
+
+// Keys returns the keys of the messages, sorted.
+func (f *Flash) Keys() []string {
+	keys := make([]string, 0, len(f.flashes))
+	for k := range f.flashes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Values returns the messages of the request, it must not be modified directly.
+func (f *Flash) Values() map[string]interface{} {
+	return f.flashes
+}
+
+// MarshalJSON returns the messages as a JSON object.
+func (f *Flash) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.flashes)
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testFlashSession(t *testing.T) *Session {
+	s, err := Parse([]byte(testSessionJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// nextRequest commits the flash and reads the session again, as the next
+// request would get it from the cookie.
+func nextRequest(t *testing.T, s *Session) *Session {
+	s.CommitFlash()
+	data, err := s.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return next
+}
+
+func TestFlashSweep(t *testing.T) {
+	s := testFlashSession(t)
+	if got := s.Flash().Notice(); got != "Welcome! You have signed up successfully." {
+		t.Fatalf("Notice = %q", got)
+	}
+	// shown once, then swept
+	next := nextRequest(t, s)
+	if next.Get(FlashKey) != nil || len(next.Flash().Keys()) != 0 {
+		t.Errorf("the flash of the previous request wasn't swept: %v", next.Get(FlashKey))
+	}
+}
+
+func TestFlashUntouched(t *testing.T) {
+	s := testFlashSession(t)
+	s.CommitFlash()
+	if s.Changed() || s.Get(FlashKey) == nil {
+		t.Errorf("CommitFlash changed a session whose flash wasn't used")
+	}
+}
+
+func TestFlashSetNowKeepDiscard(t *testing.T) {
+	s := testFlashSession(t)
+	f := s.Flash()
+	f.Set("alert", "Signed out.")
+	f.Now("info", "only now")
+	f.Set("later", "dropped")
+	f.Discard("later")
+	if got := f.Keys(); !reflect.DeepEqual(got, []string{"alert", "info", "later", "notice"}) {
+		t.Errorf("Keys = %v", got)
+	}
+
+	next := nextRequest(t, s)
+	want := `{"discard":[],"flashes":{"alert":"Signed out."}}`
+	if got, _ := json.Marshal(next.Get(FlashKey)); string(got) != want {
+		t.Errorf("stored flash = %s, want %s", got, want)
+	}
+	if next.Flash().Alert() != "Signed out." || next.Flash().Notice() != "" {
+		t.Errorf("next flash = %v", next.Flash().Values())
+	}
+
+	next.Flash().Keep("alert")
+	third := nextRequest(t, next)
+	if third.Flash().Alert() != "Signed out." {
+		t.Errorf("a kept message wasn't kept")
+	}
+	third.Flash().Keep()
+	third.Flash().Delete("alert")
+	if fourth := nextRequest(t, third); fourth.Get(FlashKey) != nil {
+		t.Errorf("a deleted message was stored: %v", fourth.Get(FlashKey))
+	}
+}
+
+func TestFlashRails41Discard(t *testing.T) {
+	// Rails 4.1 ~ 4.2 stored the discarded messages too
+	s, err := Parse([]byte(`{"flash":{"discard":["alert"],"flashes":{"alert":"old","notice":"new"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := s.Flash(); f.Alert() != "" || f.Notice() != "new" {
+		t.Errorf("flash = %v", f.Values())
+	}
+}
@@ -0,0 +1,47 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
+)
+
+const gcmTagSize = 16
+
+// DecryptGCM decrypts and authenticates a message written by
+// ActiveSupport::MessageEncryptor in GCM mode. The size of the secret picks
+// the cipher, 32 bytes for the aes-256-gcm of cookies and 16 bytes for the
+// aes-128-gcm of encrypted credentials.
+func DecryptGCM(message string, secret []byte) ([]byte, error) {
+	parts := strings.Split(message, "--")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCookie
+	}
+	var decoded [3][]byte
+	for i, p := range parts {
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return nil, ErrInvalidCookie
+		}
+		decoded[i] = b
+	}
+	data, iv, tag := decoded[0], decoded[1], decoded[2]
+	if len(tag) != gcmTagSize || len(iv) == 0 {
+		return nil, ErrInvalidCookie
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	plain, err := aead.Open(nil, iv, append(data, tag...), nil)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return plain, nil
+}
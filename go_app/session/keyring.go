@@ -0,0 +1,86 @@
+package session
+
+import (
+	"errors"
+	"net/url"
+)
+
+// Keyring decrypts cookies with an ordered list of keys, like Rails' `cookies_rotations`.
+// The first key is the current one, the others are old keys being rotated out.
+type Keyring struct {
+	Keys []Key
+	// OnDeprecated, if set, is called whenever a cookie is decrypted with an old key,
+	// e.g. to count how many sessions still need to be rotated.
+	OnDeprecated func(Match)
+	// Cache, if set, keeps the data of the decrypted cookies, see NewCache.
+	Cache *Cache
+}
+
+// Match tells which key of a keyring decrypted a cookie.
+type Match struct {
+	Index  int
+	Key    Key
+	Cipher Cipher
+}
+
+// Deprecated reports whether the cookie was decrypted with an old key.
+func (m Match) Deprecated() bool {
+	return m.Index > 0
+}
+
+// NewKeyring returns a keyring with the current key first and the old keys after it.
+func NewKeyring(current Key, old ...Key) *Keyring {
+	return &Keyring{Keys: append([]Key{current}, old...)}
+}
+
+// Decrypt tries the keys in order and returns the data of the first one that
+// verifies the cookie. ErrInvalidSignature is returned when no key matches.
+func (kr *Keyring) Decrypt(cookie string) ([]byte, *Match, error) {
+	if kr.Cache != nil {
+		if data, m, ok := kr.Cache.Get(cookie); ok {
+			if m.Deprecated() && kr.OnDeprecated != nil {
+				kr.OnDeprecated(*m)
+			}
+			return data, m, nil
+		}
+	}
+	unescaped, err := url.QueryUnescape(cookie)
+	if err != nil {
+		return nil, nil, ErrInvalidCookie
+	}
+	err = nil
+	for i, key := range kr.Keys {
+		data, e := Decrypt(cookie, key)
+		if e != nil {
+			// a signature error of any key wins over format errors of keys forcing another cipher
+			if err == nil || e == ErrInvalidSignature {
+				err = e
+			}
+			continue
+		}
+		m := &Match{Index: i, Key: key, Cipher: key.Cipher}
+		if m.Cipher == "" {
+			m.Cipher = DetectCipher(unescaped)
+		}
+		if kr.Cache != nil {
+			kr.Cache.Add(cookie, data, m)
+		}
+		if m.Deprecated() && kr.OnDeprecated != nil {
+			kr.OnDeprecated(*m)
+		}
+		return data, m, nil
+	}
+	if err == nil {
+		err = ErrInvalidSignature
+	}
+	return nil, nil, err
+}
+
+// Encrypt encrypts data with the current key, so that cookies decrypted with
+// an old key are rotated to the current one when they are written back.
+func (kr *Keyring) Encrypt(data []byte) (string, error) {
+	if len(kr.Keys) == 0 {
+		return "", errors.New("session: empty keyring")
+	}
+	return Encrypt(data, kr.Keys[0])
+}
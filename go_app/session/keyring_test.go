@@ -0,0 +1,91 @@
+package session
+
+import (
+	"testing"
+)
+
+const oldTestSecret = "6f0ad3e5bd3ae7c0b1ce6bfbc4c5e0bb1bd7fc0b09bb8c2a1ca93c44d1cc1c2a0f8a36e2c5b1a8b3e7a0f6c0f5a2e8d1d7e2c4c1b3a5f7e9d0c2b4a6f8e0a2c4"
+
+// testSessionJSON encrypted with GCM and oldTestSecret
+const gcmOldCookie = "ThSMqMe%2BNBvdGaa4aCb9SVcyVo%2FbC6Bkv0ndd760tqT1J%2BEo6hahOm6115KKGxmHBEZpJPlHUWj2y9g6hpk%2BAS%2BH6A8W3UbHXKnQGoLXPO2TFE4HAi0nPsRjhqE42SxKkzpovJRuHvNxrgWSPTmaLqPFTHA8DM99pfEutNf8IXJNspAsJsNWn1g4OBAthad3lI%2FvqO5DU54a5sJ%2F01XXfdR%2FbhoUkusqoYbLmrFHZb1wx0hOsDVBpcVjJQnp5fSO3JUFQ7Z3WzN2gTwu3M6jU%2BnAECw1heS13X7uh9qBu0WV7wW%2B52DzLV3ze2hEorauYxEGxrIxCgHuCDw6oEUDOYa7bQ%3D%3D--AQIDBAUGBwgJCgsM--T5fym4Gxp8%2FetutAtgn2uQ%3D%3D"
+
+func TestKeyringRotation(t *testing.T) {
+	kr := NewKeyring(Key{SecretKeyBase: testSecret}, Key{SecretKeyBase: oldTestSecret})
+	var deprecated []Match
+	kr.OnDeprecated = func(m Match) { deprecated = append(deprecated, m) }
+
+	data, m, err := kr.Decrypt(gcmCookie)
+	if err != nil || string(data) != testSessionJSON {
+		t.Fatalf("Decrypt current = %s, %v", data, err)
+	}
+	if m.Index != 0 || m.Deprecated() || m.Cipher != GCM {
+		t.Errorf("Decrypt current match = %+v", m)
+	}
+
+	data, m, err = kr.Decrypt(gcmOldCookie)
+	if err != nil || string(data) != testSessionJSON {
+		t.Fatalf("Decrypt old = %s, %v", data, err)
+	}
+	if m.Index != 1 || !m.Deprecated() || m.Key.SecretKeyBase != oldTestSecret {
+		t.Errorf("Decrypt old match = %+v", m)
+	}
+	if len(deprecated) != 1 || deprecated[0].Index != 1 {
+		t.Errorf("OnDeprecated calls = %+v, want the old key once", deprecated)
+	}
+
+	// the cookie written back is rotated to the current key
+	cookie, err := kr.Encrypt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(cookie, Key{SecretKeyBase: testSecret}); err != nil {
+		t.Errorf("Encrypt didn't use the current key: %v", err)
+	}
+	if _, err := Decrypt(cookie, Key{SecretKeyBase: oldTestSecret}); err != ErrInvalidSignature {
+		t.Errorf("Decrypt with the old key err = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestKeyringCache(t *testing.T) {
+	kr := NewKeyring(Key{SecretKeyBase: testSecret}, Key{SecretKeyBase: oldTestSecret})
+	kr.Cache = NewCache(8, 0)
+	calls := 0
+	kr.OnDeprecated = func(Match) { calls++ }
+	for i := 0; i < 2; i++ {
+		data, m, err := kr.Decrypt(gcmOldCookie)
+		if err != nil || string(data) != testSessionJSON || m.Index != 1 {
+			t.Fatalf("Decrypt %d = %s, %+v, %v", i, data, m, err)
+		}
+	}
+	// a cached old cookie still counts as deprecated
+	if calls != 2 {
+		t.Errorf("OnDeprecated calls = %d, want 2", calls)
+	}
+}
+
+func TestKeyringInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []Key
+		cookie string
+		err    error
+	}{
+		{"no matching key", []Key{{SecretKeyBase: oldTestSecret}}, gcmCookie, ErrInvalidSignature},
+		{"tampered", []Key{{SecretKeyBase: testSecret}, {SecretKeyBase: oldTestSecret}}, tamper(gcmOldCookie, 0), ErrInvalidSignature},
+		{"cbc key and gcm key", []Key{{SecretKeyBase: testSecret, Cipher: CBC}, {SecretKeyBase: oldTestSecret, Cipher: GCM}}, gcmCookie, ErrInvalidSignature},
+		{"empty keyring", nil, gcmCookie, ErrInvalidSignature},
+		{"bad escape", []Key{{SecretKeyBase: testSecret}}, "%zz", ErrInvalidCookie},
+	}
+	for _, tt := range tests {
+		kr := &Keyring{Keys: tt.keys}
+		if _, _, err := kr.Decrypt(tt.cookie); err != tt.err {
+			t.Errorf("%s: Decrypt err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if _, err := (&Keyring{}).Encrypt([]byte("{}")); err == nil {
+		t.Errorf("Encrypt with an empty keyring err = nil")
+	}
+	if _, err := (&Keyring{}).Sign([]byte("{}")); err == nil {
+		t.Errorf("Sign with an empty keyring err = nil")
+	}
+}
@@ -0,0 +1,367 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// ErrMarshalFormat is returned for data that isn't valid Ruby Marshal 4.8.
+var ErrMarshalFormat = errors.New("session: invalid ruby marshal data")
+
+// marshalClasses are the user classes that are allowed to wrap a Hash or a String,
+// they are decoded as their plain counterpart.
+var marshalClasses = map[string]bool{
+	"ActiveSupport::HashWithIndifferentAccess": true,
+	"ActiveSupport::SafeBuffer":                true,
+}
+
+const maxMarshalDepth = 64
+
+// IsMarshal reports whether the data starts with the Ruby Marshal 4.8 signature.
+func IsMarshal(data []byte) bool {
+	return len(data) >= 2 && data[0] == 4 && data[1] == 8
+}
+
+// UnmarshalRuby decodes the safe subset of Ruby Marshal the session data is made of:
+// nil, true, false, Integer, Float, String, Symbol, Array, Hash and
+// ActiveSupport::HashWithIndifferentAccess. Arbitrary objects are rejected.
+//
+// Values are decoded into the types encoding/json produces, except that
+// integers are int64 (or *big.Int) and symbols become strings. NaN and
+// Infinity become nil. Hash keys must be strings, symbols or integers as
+// they are converted to strings.
+func UnmarshalRuby(data []byte) (interface{}, error) {
+	if !IsMarshal(data) {
+		return nil, ErrMarshalFormat
+	}
+	d := &marshalDecoder{data: data, pos: 2}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, ErrMarshalFormat
+	}
+	return v, nil
+}
+
+type marshalDecoder struct {
+	data    []byte
+	pos     int
+	symbols []string
+	objects []interface{}
+}
+
+func (d *marshalDecoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, ErrMarshalFormat
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *marshalDecoder) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, ErrMarshalFormat
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// long reads a packed integer, the format of both Fixnums and lengths.
+func (d *marshalDecoder) long() (int, error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+	c := int(int8(b))
+	switch {
+	case c == 0:
+		return 0, nil
+	case c > 4:
+		return c - 5, nil
+	case c < -4:
+		return c + 5, nil
+	case c > 0:
+		n := 0
+		for i := 0; i < c; i++ {
+			b, err := d.byte()
+			if err != nil {
+				return 0, err
+			}
+			n |= int(b) << (8 * uint(i))
+		}
+		return n, nil
+	}
+	n := -1
+	for i := 0; i < -c; i++ {
+		b, err := d.byte()
+		if err != nil {
+			return 0, err
+		}
+		n &= ^(0xff << (8 * uint(i)))
+		n |= int(b) << (8 * uint(i))
+	}
+	return n, nil
+}
+
+func (d *marshalDecoder) rawString() (string, error) {
+	n, err := d.long()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.bytes(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// symbol reads a Symbol or a link to an already read one.
+func (d *marshalDecoder) symbol() (string, error) {
+	t, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+	switch t {
+	case ':':
+		s, err := d.rawString()
+		if err != nil {
+			return "", err
+		}
+		d.symbols = append(d.symbols, s)
+		return s, nil
+	case ';':
+		i, err := d.long()
+		if err != nil {
+			return "", err
+		}
+		if i < 0 || i >= len(d.symbols) {
+			return "", ErrMarshalFormat
+		}
+		return d.symbols[i], nil
+	case 'I':
+		// a symbol with an encoding
+		s, err := d.symbol()
+		if err != nil {
+			return "", err
+		}
+		return s, d.skipIvars()
+	}
+	return "", ErrMarshalFormat
+}
+
+// entry registers an object so that later links can point to it.
+func (d *marshalDecoder) entry(v interface{}) int {
+	d.objects = append(d.objects, v)
+	return len(d.objects) - 1
+}
+
+func (d *marshalDecoder) value(depth int) (interface{}, error) {
+	if depth > maxMarshalDepth {
+		return nil, errors.New("session: ruby marshal data nested too deep")
+	}
+	t, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case '0':
+		return nil, nil
+	case 'T':
+		return true, nil
+	case 'F':
+		return false, nil
+	case 'i':
+		n, err := d.long()
+		return int64(n), err
+	case ':', ';':
+		d.pos--
+		return d.symbol()
+	case '"':
+		s, err := d.rawString()
+		if err != nil {
+			return nil, err
+		}
+		d.entry(s)
+		return s, nil
+	case 'f':
+		s, err := d.rawString()
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseRubyFloat(s)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			// JSON has no NaN and Infinity, ActiveSupport's Float#as_json makes them null too
+			d.entry(nil)
+			return nil, nil
+		}
+		d.entry(f)
+		return f, nil
+	case 'l':
+		n, err := d.bignum()
+		if err != nil {
+			return nil, err
+		}
+		d.entry(n)
+		return n, nil
+	case '[':
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || n > len(d.data)-d.pos {
+			return nil, ErrMarshalFormat
+		}
+		arr := make([]interface{}, n)
+		idx := d.entry(arr)
+		for i := range arr {
+			if arr[i], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		d.objects[idx] = arr
+		return arr, nil
+	case '{', '}':
+		return d.hash(t == '}', depth)
+	case 'I':
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return v, d.skipIvars()
+	case 'C':
+		class, err := d.symbol()
+		if err != nil {
+			return nil, err
+		}
+		if !marshalClasses[class] {
+			return nil, fmt.Errorf("session: ruby marshal class %s is not allowed", class)
+		}
+		return d.value(depth + 1)
+	case '@':
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= len(d.objects) {
+			return nil, ErrMarshalFormat
+		}
+		return d.objects[i], nil
+	}
+	return nil, fmt.Errorf("session: ruby marshal type %q is not supported", t)
+}
+
+func (d *marshalDecoder) hash(withDefault bool, depth int) (interface{}, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, ErrMarshalFormat
+	}
+	h := make(map[string]interface{}, n)
+	d.entry(h)
+	for i := 0; i < n; i++ {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, err := hashKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if h[key], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	if withDefault {
+		// the default value of the hash isn't kept
+		if _, err := d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// skipIvars reads and drops the instance variables that follow an 'I' object,
+// which for strings is only their encoding.
+func (d *marshalDecoder) skipIvars() error {
+	n, err := d.long()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if _, err := d.symbol(); err != nil {
+			return err
+		}
+		if _, err := d.value(maxMarshalDepth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *marshalDecoder) bignum() (interface{}, error) {
+	sign, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	shorts, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	le, err := d.bytes(shorts * 2)
+	if err != nil {
+		return nil, err
+	}
+	be := make([]byte, len(le))
+	for i, b := range le {
+		be[len(le)-1-i] = b
+	}
+	n := new(big.Int).SetBytes(be)
+	if sign == '-' {
+		n.Neg(n)
+	}
+	if n.IsInt64() {
+		return n.Int64(), nil
+	}
+	return n, nil
+}
+
+func parseRubyFloat(s string) (float64, error) {
+	switch s {
+	case "nan":
+		return math.NaN(), nil
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, ErrMarshalFormat
+	}
+	return f, nil
+}
+
+func hashKey(k interface{}) (string, error) {
+	switch k := k.(type) {
+	case string:
+		return k, nil
+	case int64:
+		return strconv.FormatInt(k, 10), nil
+	case nil:
+		// it would be mixed up with an empty string key
+		return "", errors.New("session: ruby hash key nil is not supported")
+	}
+	return "", fmt.Errorf("session: unsupported ruby hash key %v", k)
+}
@@ -0,0 +1,187 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarshalRuby is the inverse of UnmarshalRuby, it dumps the values encoding/json
+// decodes into, plus int64 and *big.Int, in Ruby Marshal 4.8 format. Strings are
+// dumped as UTF-8 strings, hashes with string keys. Integral json.Numbers
+// become Integers and the others Floats.
+func MarshalRuby(v interface{}) ([]byte, error) {
+	e := &marshalEncoder{buf: []byte{4, 8}, symbols: map[string]int{}}
+	if err := e.value(v, 0); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type marshalEncoder struct {
+	buf     []byte
+	symbols map[string]int
+}
+
+func (e *marshalEncoder) long(n int) {
+	switch {
+	case n == 0:
+		e.buf = append(e.buf, 0)
+	case 0 < n && n < 123:
+		e.buf = append(e.buf, byte(n+5))
+	case -124 < n && n < 0:
+		e.buf = append(e.buf, byte(int8(n-5)))
+	default:
+		var b []byte
+		x := n
+		for i := 0; i < 4; i++ {
+			b = append(b, byte(x))
+			x >>= 8
+			if (n >= 0 && x == 0) || (n < 0 && x == -1) {
+				break
+			}
+		}
+		l := len(b)
+		if n < 0 {
+			l = -l
+		}
+		e.buf = append(append(e.buf, byte(int8(l))), b...)
+	}
+}
+
+func (e *marshalEncoder) rawString(s string) {
+	e.long(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *marshalEncoder) symbol(s string) {
+	if i, ok := e.symbols[s]; ok {
+		e.buf = append(e.buf, ';')
+		e.long(i)
+		return
+	}
+	e.symbols[s] = len(e.symbols)
+	e.buf = append(e.buf, ':')
+	e.rawString(s)
+}
+
+func (e *marshalEncoder) str(s string) {
+	e.buf = append(e.buf, 'I', '"')
+	e.rawString(s)
+	// a single instance variable, E = true, for the UTF-8 encoding
+	e.long(1)
+	e.symbol("E")
+	e.buf = append(e.buf, 'T')
+}
+
+func (e *marshalEncoder) integer(n *big.Int) {
+	// Ruby only writes the integers of 31 bits as Fixnums, the others as
+	// Bignums even on 64 bits
+	if n.IsInt64() && n.Int64() >= -1<<30 && n.Int64() < 1<<30 {
+		e.buf = append(e.buf, 'i')
+		e.long(int(n.Int64()))
+		return
+	}
+	e.buf = append(e.buf, 'l')
+	if n.Sign() < 0 {
+		e.buf = append(e.buf, '-')
+	} else {
+		e.buf = append(e.buf, '+')
+	}
+	be := new(big.Int).Abs(n).Bytes()
+	if len(be)%2 == 1 {
+		be = append([]byte{0}, be...)
+	}
+	e.long(len(be) / 2)
+	for i := len(be) - 1; i >= 0; i-- {
+		e.buf = append(e.buf, be[i])
+	}
+}
+
+func (e *marshalEncoder) float(f float64) {
+	e.buf = append(e.buf, 'f')
+	switch {
+	case math.IsNaN(f):
+		e.rawString("nan")
+	case math.IsInf(f, 1):
+		e.rawString("inf")
+	case math.IsInf(f, -1):
+		e.rawString("-inf")
+	default:
+		e.rawString(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+}
+
+func (e *marshalEncoder) value(v interface{}, depth int) error {
+	if depth > maxMarshalDepth {
+		return fmt.Errorf("session: value nested too deep to marshal")
+	}
+	switch v := v.(type) {
+	case nil:
+		e.buf = append(e.buf, '0')
+	case bool:
+		if v {
+			e.buf = append(e.buf, 'T')
+		} else {
+			e.buf = append(e.buf, 'F')
+		}
+	case string:
+		e.str(v)
+	case int:
+		e.integer(big.NewInt(int64(v)))
+	case int64:
+		e.integer(big.NewInt(v))
+	case *big.Int:
+		e.integer(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			e.integer(big.NewInt(int64(v)))
+		} else {
+			e.float(v)
+		}
+	case json.Number:
+		s := string(v)
+		if !strings.ContainsAny(s, ".eE") {
+			n, ok := new(big.Int).SetString(s, 10)
+			if !ok {
+				return fmt.Errorf("session: invalid number %q", s)
+			}
+			e.integer(n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		e.float(f)
+	case []interface{}:
+		e.buf = append(e.buf, '[')
+		e.long(len(v))
+		for _, item := range v {
+			if err := e.value(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		e.buf = append(e.buf, '{')
+		e.long(len(keys))
+		for _, k := range keys {
+			e.str(k)
+			if err := e.value(v[k], depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("session: can't marshal %T to ruby", v)
+	}
+	return nil
+}
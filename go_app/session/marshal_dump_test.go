@@ -0,0 +1,92 @@
+package session
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalRuby(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"nil", nil, "\x04\x080"},
+		{"true", true, "\x04\x08T"},
+		{"zero", int64(0), "\x04\x08i\x00"},
+		{"small", 122, "\x04\x08i\x7f"},
+		{"negative", int64(-123), "\x04\x08i\x80"},
+		{"int 300", int64(300), "\x04\x08i\x02,\x01"},
+		{"int -300", int64(-300), "\x04\x08i\xfe\xd4\xfe"},
+		{"int 2**30-1", int64(1<<30 - 1), "\x04\x08i\x04\xff\xff\xff?"},
+		{"int -2**30", int64(-1 << 30), "\x04\x08i\xfc\x00\x00\x00\xc0"},
+		{"bignum 2**30", int64(1 << 30), "\x04\x08l+\x07\x00\x00\x00@"},
+		{"bignum -2**30-1", int64(-1<<30 - 1), "\x04\x08l-\x07\x01\x00\x00@"},
+		{"bignum 2**31", int64(1 << 31), "\x04\x08l+\x07\x00\x00\x00\x80"},
+		{"bignum 2**64", bigInt("18446744073709551616"), "\x04\x08l+\x0a\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00"},
+		{"bignum -2**64", bigInt("-18446744073709551616"), "\x04\x08l-\x0a\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00"},
+		{"json integer", json.Number("300"), "\x04\x08i\x02,\x01"},
+		{"json bignum", json.Number("18446744073709551616"), "\x04\x08l+\x0a\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00"},
+		{"float", 1.5, "\x04\x08f\x081.5"},
+		{"json float", json.Number("-0.25"), "\x04\x08f\x0a-0.25"},
+		{"integral float", 2.0, "\x04\x08i\x07"},
+		{"nan", math.NaN(), "\x04\x08f\x08nan"},
+		{"string", "abc", "\x04\x08I\"\x08abc\x06:\x06ET"},
+		{"utf-8 string", "héllo ✓", "\x04\x08I\"\x0fh\xc3\xa9llo \xe2\x9c\x93\x06:\x06ET"},
+		{"hash", map[string]interface{}{"b": []interface{}{true, false}, "a": 1}, "\x04\x08{\x07I\"\x06a\x06:\x06ETi\x06I\"\x06b\x06;\x00T[\x07TF"},
+		{
+			"session",
+			map[string]interface{}{
+				"session_id":           "a3b3b33fc3336a5e29c99bbc09db714d",
+				"_csrf_token":          "Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8=",
+				"warden.user.user.key": []interface{}{[]interface{}{json.Number("2")}, "$2a$11$hGNO577ObqIlHtD/cMMRH."},
+			},
+			// the keys are sorted
+			"\x04\x08{\x08I\"\x10_csrf_token\x06:\x06ETI\"1Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8=\x06;\x00TI\"\x0fsession_id\x06;\x00TI\"%a3b3b33fc3336a5e29c99bbc09db714d\x06;\x00TI\"\x19warden.user.user.key\x06;\x00T[\x07[\x06i\x07I\"\"$2a$11$hGNO577ObqIlHtD/cMMRH.\x06;\x00T",
+		},
+	}
+	for _, tt := range tests {
+		got, err := MarshalRuby(tt.v)
+		if err != nil {
+			t.Errorf("%s: MarshalRuby err: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: MarshalRuby = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarshalRubyRoundTrip(t *testing.T) {
+	v := map[string]interface{}{
+		"flash": map[string]interface{}{"discard": []interface{}{}, "flashes": map[string]interface{}{"notice": "Signed in."}},
+		"ids":   []interface{}{int64(-1), int64(70000), bigInt("-18446744073709551617"), 0.1, nil, true, false},
+		"":      "empty key",
+	}
+	data, err := MarshalRuby(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalRuby(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// big.Int isn't comparable with DeepEqual, compare the JSON
+	want, _ := json.Marshal(v)
+	if g, _ := json.Marshal(got); string(g) != string(want) {
+		t.Errorf("UnmarshalRuby(MarshalRuby(v)) = %s, want %s", g, want)
+	}
+	if !reflect.DeepEqual(got.(map[string]interface{})["flash"], v["flash"]) {
+		t.Errorf("flash = %#v", got.(map[string]interface{})["flash"])
+	}
+}
+
+func TestMarshalRubyUnsupported(t *testing.T) {
+	for _, v := range []interface{}{struct{}{}, map[int]string{}, json.Number("1x")} {
+		if _, err := MarshalRuby(v); err == nil {
+			t.Errorf("MarshalRuby(%#v) err = nil", v)
+		}
+	}
+}
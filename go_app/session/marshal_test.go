@@ -0,0 +1,115 @@
+package session
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The Marshal data of the tests is the output of Marshal.dump in Ruby 2+,
+// written out byte by byte from the format of marshal.c.
+
+func bigInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+var marshalVectors = []struct {
+	name string
+	data string
+	want interface{}
+}{
+	{"nil", "\x04\x080", nil},
+	{"true", "\x04\x08T", true},
+	{"false", "\x04\x08F", false},
+	{"zero", "\x04\x08i\x00", int64(0)},
+	{"small", "\x04\x08i\x7f", int64(122)},
+	{"negative", "\x04\x08i\x80", int64(-123)},
+	{"int 300", "\x04\x08i\x02,\x01", int64(300)},
+	{"int -300", "\x04\x08i\xfe\xd4\xfe", int64(-300)},
+	{"int 2**30", "\x04\x08i\x04\x00\x00\x00@", int64(1 << 30)},
+	{"bignum 2**31", "\x04\x08l+\x07\x00\x00\x00\x80", int64(1 << 31)},
+	{"bignum 2**64", "\x04\x08l+\x0a\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00", bigInt("18446744073709551616")},
+	{"bignum -2**64", "\x04\x08l-\x0a\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00", bigInt("-18446744073709551616")},
+	{"float", "\x04\x08f\x081.5", 1.5},
+	{"negative float", "\x04\x08f\x0a-0.25", -0.25},
+	{"nan", "\x04\x08f\x08nan", nil},
+	{"infinity", "\x04\x08f\x08inf", nil},
+	{"string", "\x04\x08I\"\x08abc\x06:\x06ET", "abc"},
+	{"utf-8 string", "\x04\x08I\"\x0fh\xc3\xa9llo \xe2\x9c\x93\x06:\x06ET", "héllo ✓"},
+	{"binary string", "\x04\x08\"\x07\xff\x00", "\xff\x00"},
+	{"symbol", "\x04\x08:\x0bnotice", "notice"},
+	{"array with a symbol link", "\x04\x08[\x0ai\x06I\"\x06a\x06:\x06ET:\x06a;\x060", []interface{}{int64(1), "a", "a", "a", nil}},
+	{"object link", "\x04\x08[\x07I\"\x06x\x06:\x06ET@\x06", []interface{}{"x", "x"}},
+	{"float link", "\x04\x08[\x07f\x081.5@\x06", []interface{}{1.5, 1.5}},
+	{"hash", "\x04\x08{\x07I\"\x06a\x06:\x06ETi\x06I\"\x06b\x06;\x00T[\x07TF", map[string]interface{}{"a": int64(1), "b": []interface{}{true, false}}},
+	{"hash with symbol keys", "\x04\x08{\x07:\x0cuser_idi\x07:\x0ereturn_toI\"\x06/\x06:\x06ET", map[string]interface{}{"user_id": int64(2), "return_to": "/"}},
+	{"hash with an integer key", "\x04\x08{\x06i\x06I\"\x08one\x06:\x06ET", map[string]interface{}{"1": "one"}},
+	{"hash with a default", "\x04\x08}\x06I\"\x06a\x06:\x06ETi\x06i\x00", map[string]interface{}{"a": int64(1)}},
+	{"hash with non-finite floats", "\x04\x08{\x07I\"\x06a\x06:\x06ETf\x08nanI\"\x06b\x06;\x00Tf\x09-inf", map[string]interface{}{"a": nil, "b": nil}},
+	{
+		"HashWithIndifferentAccess",
+		"\x04\x08C:-ActiveSupport::HashWithIndifferentAccess{\x07I\"\x06a\x06:\x06ETi\x06I\"\x0bnested\x06;\x06TC;\x00{\x06I\"\x06b\x06;\x06TI\"\x06c\x06;\x06T",
+		map[string]interface{}{"a": int64(1), "nested": map[string]interface{}{"b": "c"}},
+	},
+	{
+		"session",
+		"\x04\x08{\x08I\"\x0fsession_id\x06:\x06ETI\"%a3b3b33fc3336a5e29c99bbc09db714d\x06;\x00TI\"\x10_csrf_token\x06;\x00TI\"1Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8=\x06;\x00TI\"\x19warden.user.user.key\x06;\x00T[\x07[\x06i\x07I\"\"$2a$11$hGNO577ObqIlHtD/cMMRH.\x06;\x00T",
+		map[string]interface{}{
+			"session_id":           "a3b3b33fc3336a5e29c99bbc09db714d",
+			"_csrf_token":          "Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8=",
+			"warden.user.user.key": []interface{}{[]interface{}{int64(2)}, "$2a$11$hGNO577ObqIlHtD/cMMRH."},
+		},
+	},
+}
+
+func TestUnmarshalRuby(t *testing.T) {
+	for _, tt := range marshalVectors {
+		got, err := UnmarshalRuby([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: UnmarshalRuby err: %v", tt.name, err)
+			continue
+		}
+		if n, ok := tt.want.(*big.Int); ok {
+			if g, ok := got.(*big.Int); !ok || g.Cmp(n) != 0 {
+				t.Errorf("%s: UnmarshalRuby = %#v, want %v", tt.name, got, n)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: UnmarshalRuby = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalRubyRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"object", "\x04\x08o:\x0bObject\x00", "type 'o' is not supported"},
+		{"user class", "\x04\x08C:\x08Foo{\x00", "class Foo is not allowed"},
+		{"user marshal", "\x04\x08U:\x0dGem::Version[\x06I\"\x0a1.0.0\x06:\x06ET", "type 'U' is not supported"},
+		{"user dump", "\x04\x08Iu:\x09Time\x0d\x00\x00\x00\x00\x00\x00\x00\x00\x06:\x06ET", "type 'u' is not supported"},
+		{"struct", "\x04\x08S:\x09Test\x00", "type 'S' is not supported"},
+		{"class", "\x04\x08c\x0bObject", "type 'c' is not supported"},
+		{"module extension", "\x04\x08e:\x0aKernel{\x00", "type 'e' is not supported"},
+		{"nil hash key", "\x04\x08{\x060i\x06", "hash key nil"},
+		{"array hash key", "\x04\x08{\x06[\x00i\x06", "unsupported ruby hash key"},
+		{"other version", "\x04\x090", "invalid ruby marshal"},
+		{"truncated", "\x04\x08I\"\x08ab", "invalid ruby marshal"},
+		{"trailing data", "\x04\x080\x00", "invalid ruby marshal"},
+		{"link out of range", "\x04\x08[\x06@\x07", "invalid ruby marshal"},
+		{"symbol link out of range", "\x04\x08;\x00", "invalid ruby marshal"},
+		{"huge array", "\x04\x08[\x04\xff\xff\xff\x7f", "invalid ruby marshal"},
+		{"nested too deep", "\x04\x08" + strings.Repeat("[\x06", 100) + "0", "nested too deep"},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalRuby([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: UnmarshalRuby err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
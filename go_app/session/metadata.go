@@ -0,0 +1,90 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrExpired is returned when the `exp` of a cookie envelope has passed.
+	ErrExpired = errors.New("session: cookie expired")
+	// ErrPurposeMismatch is returned when a cookie was written for another purpose,
+	// e.g. the value of another cookie copied into the session cookie.
+	ErrPurposeMismatch = errors.New("session: cookie purpose mismatch")
+)
+
+// Metadata is the information Rails 6+ embeds in the `_rails` envelope of a cookie.
+type Metadata struct {
+	Purpose string
+	// Expires is zero when the cookie has no expiry.
+	Expires time.Time
+}
+
+type envelope struct {
+	Rails *envelopeFields `json:"_rails"`
+}
+
+type envelopeFields struct {
+	Message *string         `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Exp     *string         `json:"exp"`
+	Pur     *string         `json:"pur"`
+}
+
+var envelopePrefix = []byte(`{"_rails":`)
+
+// now is replaceable to check the expiry against a fixed time.
+var now = time.Now
+
+// Purpose returns the purpose Rails uses for the cookie with the given name.
+func Purpose(cookieName string) string {
+	return "cookie." + cookieName
+}
+
+// Unwrap verifies and strips the `_rails` envelope of decrypted cookie data.
+// The purpose must match and the expiry must not have passed.
+// Data without an envelope, as written before Rails 6, is returned unchanged with nil metadata.
+func Unwrap(data []byte, purpose string) ([]byte, *Metadata, error) {
+	if !bytes.HasPrefix(data, envelopePrefix) {
+		return data, nil, nil
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Rails == nil {
+		return data, nil, nil
+	}
+
+	md := &Metadata{}
+	if env.Rails.Pur != nil {
+		md.Purpose = *env.Rails.Pur
+	}
+	if md.Purpose != purpose {
+		return nil, md, ErrPurposeMismatch
+	}
+	if env.Rails.Exp != nil {
+		exp, err := time.Parse(time.RFC3339, *env.Rails.Exp)
+		if err != nil {
+			return nil, md, ErrInvalidCookie
+		}
+		md.Expires = exp
+		if !now().Before(exp) {
+			return nil, md, ErrExpired
+		}
+	}
+
+	switch {
+	case env.Rails.Message != nil:
+		// Rails 6.0 ~ 7.0 keep the serialized session base64 encoded
+		msg, err := base64.StdEncoding.DecodeString(*env.Rails.Message)
+		if err != nil {
+			return nil, md, ErrInvalidCookie
+		}
+		return msg, md, nil
+	case env.Rails.Data != nil:
+		// Rails 7.1+ embed the session itself
+		return env.Rails.Data, md, nil
+	}
+	return nil, md, ErrInvalidCookie
+}
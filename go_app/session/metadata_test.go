@@ -0,0 +1,122 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// Rails 6.0 ~ 6.1, GCM with a SHA1 key and the message envelope
+	gcmMetadataCookie = "cNIjXvIO5gcXtiY1L16w8cyIFPPnPQdki8qb58%2BGpVvKkwjE8ydiyEMmyYQ5miiEryxC6p7cJcrd6aRhBwoacp3WGeQbzNWyULgR6YEp2xFdvdH9UePXQS%2BshjlafDh3rxj2LlNoH6Z5hw%2FIoCbXzdoMX%2F76SSdVyGOeL%2Fe178QFGDpw2SH8sc1v1VITI95ytQ%2FYqkUb%2FbDl90jygGpDooGpduml%2B7TXVgXg8gV77vcvfqZ6eRHcjQyOhuxesn%2FYVRri5wAwI0NNqUYEkVkArgHvtcptj%2Bbvsp0VQzu%2FoU%2F75VcQ9hcZOMiVfm9nDJxCpPulT1L3Ja0g9%2BtNO8J9oV4%2BoUpF%2BRyxwXpELLjpkp%2BgiQUm4Hw2gR%2Fu%2BXYNIHUJJYu3C%2BmNFRqIbP7nrYA6F1cCjUE9UXNisA7wdf4nSSL1izr2I49867FjVUsOxzE5XyjXtLzrxluFNzl9huxUwF0SjHywS7UzD4ACx%2FmIVsxhl3sYyiAIiaDWbmUtIcHIWnncZatnwkls%2FuQBpD4akASo8n4D3jfWDedOSRINccKO2Q4WesCJaQBqaLqbopjSYlx1--AQIDBAUGBwgJCgsM--JV9%2Fb2d4ggXK1uPQtv9Yig%3D%3D"
+	// Rails 7.0, GCM with a SHA256 key and the message envelope
+	gcmSHA256Cookie = "JXqkL2%2FvvNuIOjWFlyzlzfoLmnMyl9TCQBT8VfjGP9fiXh4PVZ0YbIT1hu1N8jZpJymorC6ZWZ%2Fot0Q8q3C46vdsaeIaAHqJeM5WKH%2Bm3AnpHDvZq4cVYuO4wB%2Fb%2FnxXFSb6GwgFUHoCAe3qJkkN%2FM72BbX%2FTY0t8II6haOtg1qhukxJMcplqb9g5DC0xq9bGoIBIQlX%2BKZv0ID%2Bxy0MozKjEn%2Ba8IuyGtcyjud2AaRvTPHGUR99hjseWpdfQLdmOC7iIjGMBUMaq192coh3fSVcsWegywwEgdcL04eKsqtwHkPwXkjA%2BxZfiM44VGJD7EcgXsvwP8zMvo4noGNLgQb%2FI25CLsBlh41TW1rmVmJ4K3cgJxp2p1DAofZ96Vv50Je8VLOc%2FSs43f%2FRiBQ6Ix9XYfwN4JcQf5ZUo3eYSOQ71Mb00Y46THNBqAPyf%2BYHUyubItb5%2F6G5%2FIoe9CdA1WWtyc%2BwODMC%2BiCsTLV%2B2IS3%2BbA8j1Z%2Bdn8rwcQIgVgtDL4H3x5jx%2Fg82GJCS8Yd1csdNOq9XpCL7YCN5PARj96ixVlmzQcz3McgjCbMThvZHBtD--AQIDBAUGBwgJCgsM--yEzwBH8ymV6Ox3CsIrsvow%3D%3D"
+	// Rails 7.1+, the session embedded in the envelope
+	gcmDataCookie = "JXqkL2%2FvvNuIOjWFniji37lWhHN70MLIYwHIUt%2FYfLr5SnVfCpRiNY3%2F7IowrRpuC2Hc%2FU3KdofG%2FTM1jHbTitp7HLouVlCWVPtvA3io60GwRiTvzplvVZCJrUbAzldIbV3SHz0CQFocB8vMN3wv2OCzAInCZ6Q%2B1I5Z9t3q8CP3l25DBNAk%2BKgazR7%2B7pgZT9NZP2VIg5hr45D7xgt43yb2N2qJoKG%2BT4RKtPpyAfllQJ%2BIexlS4T8FdcA5SoRCH33RM3SPbF01u2VLT5RLUARZhyfx3xNFmpAL9q2Dle9qBHCwJk7qrF0FufgEEQ9PjkQ0NYG8acC96NwxpEpno2WjXWl2KMBBgchORnfHeAN%2BaDEqIkUxhBHb5ZRv%2BX3435a6F76V5yNgnPCGgR8TLC1yScUt%2BYV9WsZuum6VccEC1fnE8YoZA0tX--AQIDBAUGBwgJCgsM--fyPo7K5cSfJgqUmdxNifgw%3D%3D"
+	// Rails 7.0 with an envelope expired on 2020-01-01
+	gcmExpiredCookie = "JXqkL2%2FvvNuIOjWFlyzlzfoLmnMyl9TCQBT8VfjGP9fiXh4PVZ0YbIT1hu1N8jZpJymorC6ZWZ%2Fot0Q8q3C46vdsaeIaAHqJeM5WKH%2Bm3AnpHDvZq4cVYuO4wB%2Fb%2FnxXFSb6GwgFUHoCAe3qJkkN%2FM72BbX%2FTY0t8II6haOtg1qhukxJMcplqb9g5DC0xq9bGoIBIQlX%2BKZv0ID%2Bxy0MozKjEn%2Ba8IuyGtcyjud2AaRvTPHGUR99hjseWpdfQLdmOC7iIjGMBUMaq192coh3fSVcsWegywwEgdcL04eKsqtwHkPwXkjA%2BxZfiM44VGJD7EcgXsvwP8zMvo4noGNLgQb%2FI25CLsBlh41TW1rmVmJ4K3cgJxp2p1DAofZ96Vv50Je8VLOc%2FSs43f%2FRiBQ6Ix9XYfwN4JcQf5ZUo3eYSOQ71Mb00Y46THNBqAPyf%2BYHUyubItb5%2F6G5%2FIoe9CdA1WWtyc%2BwODMC%2BiCsTLV%2B2IS3%2BbA8j1Z%2Bdn8rjYNU30QiTPpYzRUVlKdpgzsdValWnZpAHqT0I5Kb%2FsbotPIXjMaU0xJK2xY93%2Bg%2FjArNQhXTYRRfKSE195YIxonFEbEik%2Bji%2B0PKQj8Q7Q%3D%3D--AQIDBAUGBwgJCgsM--foAteD1D5%2BCO7Rk04PHjrQ%3D%3D"
+	// Rails 7.0 with the purpose of the remember cookie
+	gcmOtherPurposeCookie = "JXqkL2%2FvvNuIOjWFlyzlzfoLmnMyl9TCQBT8VfjGP9fiXh4PVZ0YbIT1hu1N8jZpJymorC6ZWZ%2Fot0Q8q3C46vdsaeIaAHqJeM5WKH%2Bm3AnpHDvZq4cVYuO4wB%2Fb%2FnxXFSb6GwgFUHoCAe3qJkkN%2FM72BbX%2FTY0t8II6haOtg1qhukxJMcplqb9g5DC0xq9bGoIBIQlX%2BKZv0ID%2Bxy0MozKjEn%2Ba8IuyGtcyjud2AaRvTPHGUR99hjseWpdfQLdmOC7iIjGMBUMaq192coh3fSVcsWegywwEgdcL04eKsqtwHkPwXkjA%2BxZfiM44VGJD7EcgXsvwP8zMvo4noGNLgQb%2FI25CLsBlh41TW1rmVmJ4K3cgJxp2p1DAofZ96Vv50Je8VLOc%2FSs43f%2FRiBQ6Ix9XYfwN4JcQf5ZUo3eYSOQ71Mb00Y46THNBqAPyf%2BYHUyubItb5%2F6G5%2FIoe9CdA1WWtyc%2BwODMC%2BiCsTLV%2B2IS3%2BbA8j1Z%2Bdn8rwcQIgVgtDL4H3x5jx%2Fg82GJCS%2BsdwM8dJuOqXped6ZaN4v4ThsPfy0E%3D--AQIDBAUGBwgJCgsM--y1XQKr7FNmdk7emV9lyRvA%3D%3D"
+)
+
+func TestUnwrap(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		digest Digest
+	}{
+		{"rails 6 message", gcmMetadataCookie, SHA1},
+		{"rails 7.0 sha256 message", gcmSHA256Cookie, SHA256},
+		{"rails 7.1 data", gcmDataCookie, SHA256},
+		{"no envelope", gcmCookie, SHA1},
+	}
+	for _, tt := range tests {
+		data, err := Decrypt(tt.cookie, Key{SecretKeyBase: testSecret, Digest: tt.digest})
+		if err != nil {
+			t.Errorf("%s: Decrypt err: %v", tt.name, err)
+			continue
+		}
+		msg, md, err := Unwrap(data, Purpose(testCookieName))
+		if err != nil {
+			t.Errorf("%s: Unwrap err: %v", tt.name, err)
+			continue
+		}
+		if string(msg) != testSessionJSON {
+			t.Errorf("%s: Unwrap = %s, want %s", tt.name, msg, testSessionJSON)
+		}
+		if md != nil && (md.Purpose != "cookie."+testCookieName || !md.Expires.IsZero()) {
+			t.Errorf("%s: Unwrap metadata = %+v", tt.name, md)
+		}
+	}
+}
+
+func TestUnwrapRejected(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		cookie  string
+		purpose string
+		err     error
+	}{
+		{"expired", gcmExpiredCookie, Purpose(testCookieName), ErrExpired},
+		{"cookie of another purpose", gcmOtherPurposeCookie, Purpose(testCookieName), ErrPurposeMismatch},
+		{"read as another cookie", gcmSHA256Cookie, Purpose("remember_user_token"), ErrPurposeMismatch},
+	}
+	for _, tt := range tests {
+		data, err := Decrypt(tt.cookie, Key{SecretKeyBase: testSecret, Digest: SHA256})
+		if err != nil {
+			t.Errorf("%s: Decrypt err: %v", tt.name, err)
+			continue
+		}
+		if _, _, err := Unwrap(data, tt.purpose); err != tt.err {
+			t.Errorf("%s: Unwrap err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+	expires := time.Date(2023, 1, 15, 12, 30, 0, 0, time.UTC)
+
+	wrapped, err := Wrap([]byte(testSessionJSON), Purpose(testCookieName), expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the format of Messages::Metadata, with the milliseconds of Rails' exp
+	if want := `"exp":"2023-01-15T12:30:00.000Z","pur":"cookie._example_read_rails_session_session"}}`; !strings.HasSuffix(string(wrapped), want) {
+		t.Errorf("Wrap = %s, want a suffix %s", wrapped, want)
+	}
+	data, md, err := Unwrap(wrapped, Purpose(testCookieName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != testSessionJSON || !md.Expires.Equal(expires) {
+		t.Errorf("Unwrap(Wrap) = %s, %+v", data, md)
+	}
+
+	now = func() time.Time { return expires }
+	if _, _, err := Unwrap(wrapped, Purpose(testCookieName)); err != ErrExpired {
+		t.Errorf("Unwrap at the expiry err = %v, want ErrExpired", err)
+	}
+}
+
+func TestUnwrapInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"bad exp", `{"_rails":{"message":"e30=","exp":"tomorrow","pur":"cookie.x"}}`, ErrInvalidCookie},
+		{"bad message", `{"_rails":{"message":"%%%","exp":null,"pur":"cookie.x"}}`, ErrInvalidCookie},
+		{"no message", `{"_rails":{"exp":null,"pur":"cookie.x"}}`, ErrInvalidCookie},
+		{"no purpose", `{"_rails":{"message":"e30=","exp":null}}`, ErrPurposeMismatch},
+	}
+	for _, tt := range tests {
+		if _, _, err := Unwrap([]byte(tt.data), "cookie.x"); err != tt.err {
+			t.Errorf("%s: Unwrap err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package session
+
+// RailsSession are the keys Rails and Devise keep in a session, typed.
+type RailsSession struct {
+	SessionID string `json:"session_id" session:"session_id"`
+	// CSRFToken is the real token of the session, not one for a page, see MaskedCSRFToken.
+	CSRFToken string `json:"csrf_token,omitempty" session:"_csrf_token"`
+	// Flash are the stored messages, as they were written by the previous request.
+	Flash map[string]interface{} `json:"flash,omitempty" session:"-"`
+	// Warden are the users signed in with Devise, by scope.
+	Warden map[string]*WardenUser `json:"warden,omitempty" session:"-"`
+	// Values is all the data of the session.
+	Values map[string]interface{} `json:"values" session:"-"`
+}
+
+// Rails returns the typed Rails keys of the session, the flash isn't swept.
+// An error is returned when one of them isn't in the Rails format.
+func (s *Session) Rails() (*RailsSession, error) {
+	rs := &RailsSession{Values: s.values}
+	if err := s.Decode(rs); err != nil {
+		return nil, err
+	}
+
+	var stored struct {
+		Flash *struct {
+			Flashes map[string]interface{} `session:"flashes"`
+		} `session:"flash"`
+	}
+	if err := s.Decode(&stored); err != nil {
+		return nil, err
+	}
+	if stored.Flash != nil && len(stored.Flash.Flashes) > 0 {
+		rs.Flash = stored.Flash.Flashes
+	}
+
+	for _, scope := range s.WardenScopes() {
+		u, err := s.WardenUser(scope)
+		if err == ErrNoWardenUser {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rs.Warden == nil {
+			rs.Warden = map[string]*WardenUser{}
+		}
+		rs.Warden[scope] = u
+	}
+	return rs, nil
+}
@@ -0,0 +1,197 @@
+package session
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisBackend stores the sessions in Redis like redis-session-store, under
+// the Prefix and the session id. It speaks the Redis protocol itself, with a
+// single connection that's reopened after an error.
+type RedisBackend struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix is redis-session-store's `key_prefix`.
+	Prefix  string
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewRedisBackend returns a backend for a Redis URL like
+// redis://:password@localhost:6379/0, with the key prefix of the sessions.
+func NewRedisBackend(rawurl, prefix string) (*RedisBackend, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("session: unsupported redis url scheme %q", u.Scheme)
+	}
+	b := &RedisBackend{Addr: u.Host, Prefix: prefix, Timeout: 5 * time.Second}
+	if u.Port() == "" {
+		b.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		b.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if b.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("session: invalid redis db %q", db)
+		}
+	}
+	return b, nil
+}
+
+func (b *RedisBackend) Get(id string) ([]byte, error) {
+	v, err := b.do("GET", b.Prefix+id)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+func (b *RedisBackend) Set(id string, data []byte, ttl time.Duration) error {
+	args := []string{"SET", b.Prefix + id, string(data)}
+	if ttl > 0 {
+		// rounded up, Redis refuses EX 0 and a shorter TTL would expire early
+		secs := (ttl + time.Second - 1) / time.Second
+		args = append(args, "EX", strconv.FormatInt(int64(secs), 10))
+	}
+	_, err := b.do(args...)
+	return err
+}
+
+func (b *RedisBackend) Delete(id string) error {
+	_, err := b.do("DEL", b.Prefix+id)
+	return err
+}
+
+// Close closes the connection.
+func (b *RedisBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// do runs a command and returns its reply, the errors of the server are
+// returned as redisError and keep the connection open.
+func (b *RedisBackend) do(args ...string) (interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		if err := b.dial(); err != nil {
+			return nil, err
+		}
+	}
+	v, err := b.roundTrip(args)
+	if _, ok := err.(redisError); err != nil && !ok {
+		b.conn.Close()
+		b.conn = nil
+	}
+	return v, err
+}
+
+func (b *RedisBackend) dial() error {
+	conn, err := net.DialTimeout("tcp", b.Addr, b.Timeout)
+	if err != nil {
+		return err
+	}
+	b.conn, b.rd = conn, bufio.NewReader(conn)
+	if b.Password != "" {
+		if _, err := b.roundTrip([]string{"AUTH", b.Password}); err != nil {
+			conn.Close()
+			b.conn = nil
+			return err
+		}
+	}
+	if b.DB != 0 {
+		if _, err := b.roundTrip([]string{"SELECT", strconv.Itoa(b.DB)}); err != nil {
+			conn.Close()
+			b.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *RedisBackend) roundTrip(args []string) (interface{}, error) {
+	if b.Timeout > 0 {
+		b.conn.SetDeadline(time.Now().Add(b.Timeout))
+	}
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(b.conn, cmd.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(b.rd)
+}
+
+// readRedisReply reads a reply of the Redis protocol (RESP): the bulk strings
+// are []byte, nil for a missing key.
+func readRedisReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, errors.New("redis: invalid reply")
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRedisReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, errors.New("redis: invalid reply")
+}
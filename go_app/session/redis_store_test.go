@@ -0,0 +1,236 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a Redis server of the commands RedisBackend sends, like
+// miniredis, it keeps the values and the TTLs of the keys of each db.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeRedis{ln: ln, password: password, data: map[string][]byte{}, ttls: map[string]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) Close() { r.ln.Close() }
+
+func (r *fakeRedis) URL(db int) string {
+	if r.password != "" {
+		return fmt.Sprintf("redis://:%s@%s/%d", r.password, r.ln.Addr(), db)
+	}
+	return fmt.Sprintf("redis://%s/%d", r.ln.Addr(), db)
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed, db := r.password == "", "0"
+	for {
+		v, err := readRedisReply(rd)
+		if err != nil {
+			return
+		}
+		items, _ := v.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		r.mu.Lock()
+		switch {
+		case cmd == "AUTH" && len(args) == 2:
+			if args[1] != r.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				break
+			}
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case cmd == "SELECT" && len(args) == 2:
+			db = args[1]
+			fmt.Fprint(conn, "+OK\r\n")
+		case cmd == "GET" && len(args) == 2:
+			if v, ok := r.data[db+":"+args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case cmd == "SET" && (len(args) == 3 || len(args) == 5):
+			key := db + ":" + args[1]
+			delete(r.ttls, key)
+			if len(args) == 5 {
+				if n, err := strconv.Atoi(args[4]); strings.ToUpper(args[3]) != "EX" || err != nil || n <= 0 {
+					fmt.Fprint(conn, "-ERR invalid expire time in 'set' command\r\n")
+					break
+				}
+				r.ttls[key] = args[4]
+			}
+			r.data[key] = []byte(args[2])
+			fmt.Fprint(conn, "+OK\r\n")
+		case cmd == "DEL" && len(args) == 2:
+			n := 0
+			if _, ok := r.data[db+":"+args[1]]; ok {
+				n = 1
+			}
+			delete(r.data, db+":"+args[1])
+			delete(r.ttls, db+":"+args[1])
+			fmt.Fprintf(conn, ":%d\r\n", n)
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *fakeRedis) get(key string) ([]byte, string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.data[key]
+	return v, r.ttls[key], ok
+}
+
+func TestRedisBackend(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	defer srv.Close()
+	b, err := NewRedisBackend(srv.URL(2), "session:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if data, err := b.Get("missing"); err != nil || data != nil {
+		t.Errorf("Get of a missing id = %q, %v", data, err)
+	}
+	tests := []struct {
+		ttl  time.Duration
+		want string
+	}{
+		{0, ""},
+		{time.Hour, "3600"},
+		{1500 * time.Millisecond, "2"},
+		{time.Millisecond, "1"},
+	}
+	for _, tt := range tests {
+		if err := b.Set("abc", []byte(`{"a":1}`), tt.ttl); err != nil {
+			t.Errorf("Set with the ttl %v err: %v", tt.ttl, err)
+			continue
+		}
+		if _, ttl, _ := srv.get("2:session:abc"); ttl != tt.want {
+			t.Errorf("Set with the ttl %v sent EX %q, want %q", tt.ttl, ttl, tt.want)
+		}
+	}
+	if data, err := b.Get("abc"); err != nil || string(data) != `{"a":1}` {
+		t.Errorf("Get = %q, %v", data, err)
+	}
+	if err := b.Delete("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := srv.get("2:session:abc"); ok {
+		t.Errorf("Delete left the key")
+	}
+
+	wrong, _ := NewRedisBackend(strings.Replace(srv.URL(0), "secret", "wrong", 1), "")
+	defer wrong.Close()
+	if _, err := wrong.Get("abc"); err == nil {
+		t.Errorf("Get with a wrong password err = nil")
+	}
+}
+
+func TestRedisServerStore(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	defer srv.Close()
+	b, err := NewRedisBackend(srv.URL(0), "session:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	st := &ServerStore{
+		Backend:    b,
+		Serializer: JSONSerializer,
+		Options:    CookieOptions{Name: testCookieName, HTTPOnly: true, ExpireAfter: 500 * time.Millisecond},
+	}
+
+	s := New(nil)
+	s.Set("user_return_to", "/orders")
+	w := httptest.NewRecorder()
+	if err := st.Save(w, httptest.NewRequest("GET", "/", nil), s); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly {
+		t.Fatalf("Save cookies = %+v", cookies)
+	}
+	id := cookies[0].Value
+	data, ttl, ok := srv.get("0:session:" + PrivateID(id))
+	if !ok || string(data) != `{"user_return_to":"/orders"}` || ttl != "1" {
+		t.Errorf("stored session = %s (EX %q), %v", data, ttl, ok)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	loaded, err := st.Load(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Exists() || loaded.Get("session_id") != id || loaded.Get("user_return_to") != "/orders" {
+		t.Errorf("Load(Save) = %+v", loaded.Values())
+	}
+
+	// a renewed session destroys the old one
+	loaded.Renew()
+	w = httptest.NewRecorder()
+	if err := st.Save(w, r, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := srv.get("0:session:" + PrivateID(id)); ok {
+		t.Errorf("the old session is still stored after Renew")
+	}
+	if old, err := st.Load(r); err != nil || old.Exists() {
+		t.Errorf("Load of the old session id = %+v, %v", old, err)
+	}
+	renewed := w.Result().Cookies()
+	if len(renewed) != 1 || renewed[0].Value == id {
+		t.Fatalf("Save of the renewed session cookies = %+v", renewed)
+	}
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: testCookieName, Value: renewed[0].Value})
+	if again, err := st.Load(r); err != nil || again.Get("user_return_to") != "/orders" {
+		t.Errorf("Load of the renewed session = %+v, %v", again, err)
+	}
+}
@@ -0,0 +1,66 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// RememberToken is the value of Devise's signed remember cookie, e.g.
+// `remember_user_token`: [[id], remember_token, generated_at], the token being
+// the authenticatable salt for models without a remember_token column.
+type RememberToken struct {
+	ID          int64
+	Token       string
+	GeneratedAt time.Time
+}
+
+// RememberCookieName returns the name of the remember cookie of the Devise scope.
+func RememberCookieName(scope string) string {
+	return "remember_" + scope + "_token"
+}
+
+var errRememberFormat = errors.New("session: remember token isn't [[id], token, generated_at]")
+
+var floatTimestamp = regexp.MustCompile(`^\d+\.\d+$`)
+
+// ParseRememberToken decodes the JSON data of a remember cookie.
+func ParseRememberToken(data []byte) (*RememberToken, error) {
+	var record []interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&record); err != nil {
+		return nil, err
+	}
+	if len(record) != 3 {
+		return nil, errRememberFormat
+	}
+	ids, ok := record[0].([]interface{})
+	if !ok || len(ids) != 1 {
+		return nil, errRememberFormat
+	}
+	id, err := toInt64(ids[0])
+	if err != nil {
+		return nil, err
+	}
+	token, _ := record[1].(string)
+	generatedAt, _ := record[2].(string)
+	t := &RememberToken{ID: id, Token: token}
+	// Devise writes Time#to_f as a string, and parses anything else with Time.parse
+	if floatTimestamp.MatchString(generatedAt) {
+		f, _ := strconv.ParseFloat(generatedAt, 64)
+		t.GeneratedAt = time.Unix(0, int64(f*float64(time.Second)))
+	} else if at, err := time.Parse(time.RFC3339Nano, generatedAt); err == nil {
+		t.GeneratedAt = at
+	}
+	return t, nil
+}
+
+// MarshalJSON encodes the token the way Devise's serialize_into_cookie does.
+func (t *RememberToken) MarshalJSON() ([]byte, error) {
+	at := float64(t.GeneratedAt.UnixNano()) / float64(time.Second)
+	return json.Marshal([]interface{}{[]int64{t.ID}, t.Token, strconv.FormatFloat(at, 'f', 6, 64)})
+}
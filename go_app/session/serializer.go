@@ -0,0 +1,62 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Serializer is the format of the session data,
+// as in Rails' `config.action_dispatch.cookies_serializer`.
+type Serializer string
+
+const (
+	JSONSerializer    Serializer = "json"
+	MarshalSerializer Serializer = "marshal"
+	// HybridSerializer reads both Marshal and JSON data, the format is detected per cookie.
+	HybridSerializer Serializer = "hybrid"
+)
+
+// Deserialize converts the serialized session data to JSON, so a session
+// written with any of the Rails serializers can be read in the same way.
+// Marshal data produces the same JSON the Rails JSON serializer would have written.
+func Deserialize(data []byte, serializer Serializer) ([]byte, error) {
+	switch serializer {
+	case JSONSerializer, "":
+		return data, nil
+	case MarshalSerializer:
+		return marshalToJSON(data)
+	case HybridSerializer:
+		if IsMarshal(data) {
+			return marshalToJSON(data)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("session: unknown serializer %q", serializer)
+}
+
+// Serialize is the inverse of Deserialize, it converts JSON session data to the
+// format of the serializer. Like Rails, the hybrid serializer writes JSON.
+func Serialize(data []byte, serializer Serializer) ([]byte, error) {
+	switch serializer {
+	case JSONSerializer, HybridSerializer, "":
+		return data, nil
+	case MarshalSerializer:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return MarshalRuby(v)
+	}
+	return nil, fmt.Errorf("session: unknown serializer %q", serializer)
+}
+
+func marshalToJSON(data []byte) ([]byte, error) {
+	v, err := UnmarshalRuby(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
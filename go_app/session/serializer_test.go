@@ -0,0 +1,79 @@
+package session
+
+import (
+	"testing"
+)
+
+// Rails 4.0 ~ 5.1, CBC + HMAC-SHA1 with the Marshal serializer
+const cbcMarshalCookie = "RVlCTWpCdFdiUFJRNzk0TmM5YXNGU3ZER1FpMHdBVlRSOEhDT3NoUEVuSFQ0Y1c5enJDVGMyM0E4VjdmcjV5d2g5bUxSRnFsT2F3WDBRQ05jMlZBUTNRdTUwNEc1aXV0SWdodUdiRjQ5aCtuUEdMR1NkakxmUWVrQ24rZFRWQ0ZkRi8yRFc1dTB4LzE2dW9GRmx5aW1VUTZTdmVYcjI5b0plWCsxdnQ1NmVKMDBpOTZqOVVRWDdOQkdsUlVCZ0ZJLS1BUUlEQkFVR0J3Z0pDZ3NNRFE0UEVBPT0%3D--8df13073e9902c60ab9dfac4942bc39ad4492092"
+
+func TestDeserialize(t *testing.T) {
+	marshalData, err := Decrypt(cbcMarshalCookie, Key{SecretKeyBase: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the JSON encoding sorts the keys
+	marshalJSON := `{"_csrf_token":"Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8=","session_id":"a3b3b33fc3336a5e29c99bbc09db714d"}`
+
+	tests := []struct {
+		name       string
+		data       []byte
+		serializer Serializer
+		want       string
+	}{
+		{"marshal", marshalData, MarshalSerializer, marshalJSON},
+		{"hybrid marshal", marshalData, HybridSerializer, marshalJSON},
+		{"hybrid json", []byte(testSessionJSON), HybridSerializer, testSessionJSON},
+		{"json", []byte(testSessionJSON), JSONSerializer, testSessionJSON},
+		{"default", []byte(testSessionJSON), "", testSessionJSON},
+		{"non-finite floats", []byte("\x04\x08{\x07I\"\x06a\x06:\x06ETf\x08nanI\"\x06b\x06;\x00Tf\x081.5"), MarshalSerializer, `{"a":null,"b":1.5}`},
+	}
+	for _, tt := range tests {
+		got, err := Deserialize(tt.data, tt.serializer)
+		if err != nil {
+			t.Errorf("%s: Deserialize err: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: Deserialize = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeserializeRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		serializer Serializer
+	}{
+		{"json as marshal", testSessionJSON, MarshalSerializer},
+		{"object", "\x04\x08o:\x0bObject\x00", HybridSerializer},
+		{"unknown serializer", testSessionJSON, "yaml"},
+	}
+	for _, tt := range tests {
+		if _, err := Deserialize([]byte(tt.data), tt.serializer); err == nil {
+			t.Errorf("%s: Deserialize err = nil", tt.name)
+		}
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	data, err := Serialize([]byte(testSessionJSON), MarshalSerializer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsMarshal(data) {
+		t.Fatalf("Serialize = %q, want Marshal data", data)
+	}
+	back, err := Deserialize(data, MarshalSerializer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted := `{"_csrf_token":"Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8=","flash":{"discard":[],"flashes":{"notice":"Welcome! You have signed up successfully."}},"session_id":"a3b3b33fc3336a5e29c99bbc09db714d","warden.user.user.key":[[2],"$2a$11$hGNO577ObqIlHtD/cMMRH."]}`
+	if string(back) != sorted {
+		t.Errorf("Deserialize(Serialize) = %s, want %s", back, sorted)
+	}
+	if data, err := Serialize([]byte(testSessionJSON), HybridSerializer); err != nil || string(data) != testSessionJSON {
+		t.Errorf("Serialize hybrid = %s, %v, want the JSON", data, err)
+	}
+}
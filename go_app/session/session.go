@@ -0,0 +1,150 @@
+// Package session decrypts the session cookies written by the Rails CookieStore.
+//
+// Both of the cookie formats Rails has used are supported:
+//
+//   - AES-256-CBC + HMAC-SHA1, the default before Rails 5.2, encoded as
+//     base64(base64(data)--base64(iv))--hex(hmac)
+//   - AES-256-GCM, the default since Rails 5.2, encoded as
+//     base64(data)--base64(iv)--base64(auth_tag)
+package session
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Cipher is the name of the cipher a cookie was encrypted with,
+// as in Rails' `config.action_dispatch.encrypted_cookie_cipher`.
+type Cipher string
+
+const (
+	// CBC is the cipher used by Rails 4 up to 5.1.
+	CBC Cipher = "aes-256-cbc"
+	// GCM is the cipher used by Rails 5.2 and later.
+	GCM Cipher = "aes-256-gcm"
+)
+
+// Digest is the hash function the Rails key generator derives keys with,
+// as in `config.active_support.key_generator_hash_digest_class`.
+type Digest string
+
+const (
+	// SHA1 is the key generator digest used before Rails 7.
+	SHA1 Digest = "SHA1"
+	// SHA256 is the key generator digest used since Rails 7.
+	SHA256 Digest = "SHA256"
+)
+
+// Default salts of the Rails cookie jars.
+const (
+	EncryptedCookieSalt              = "encrypted cookie"
+	EncryptedSignedCookieSalt        = "signed encrypted cookie"
+	AuthenticatedEncryptedCookieSalt = "authenticated encrypted cookie"
+	SignedCookieSalt                 = "signed cookie"
+)
+
+const keyIterations = 1000
+
+var (
+	// ErrInvalidCookie is returned when a cookie isn't in any known format.
+	ErrInvalidCookie = errors.New("session: invalid cookie format")
+	// ErrInvalidSignature is returned when a cookie has been tampered with
+	// or was written with another secret.
+	ErrInvalidSignature = errors.New("session: invalid cookie signature")
+)
+
+// Key holds everything needed to decrypt the cookies of a Rails app.
+// Empty salts fall back to the Rails defaults.
+type Key struct {
+	SecretKeyBase string
+	// Cipher forces a cookie format, leave it empty to detect the format per cookie.
+	Cipher Cipher
+	// Digest of the key generator, SHA1 if empty.
+	Digest            Digest
+	Salt              string // salt of the CBC encryption key
+	SignSalt          string // salt of the CBC signing key
+	AuthenticatedSalt string // salt of the GCM encryption key
+	SignedSalt        string // salt of the signed cookies key
+	// SignedDigest is the HMAC digest of the signed cookies, SHA1 if empty,
+	// as in `config.action_dispatch.signed_cookie_digest`.
+	SignedDigest Digest
+}
+
+// Decrypt verifies and decrypts a session cookie value with the key,
+// returning the serialized session data.
+func Decrypt(cookie string, key Key) ([]byte, error) {
+	cookie, err := url.QueryUnescape(cookie)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	cipher := key.Cipher
+	if cipher == "" {
+		cipher = DetectCipher(cookie)
+	}
+	switch cipher {
+	case CBC:
+		return decryptCBC(cookie, key.deriveKey(orDefault(key.Salt, EncryptedCookieSalt), 32), key.deriveKey(orDefault(key.SignSalt, EncryptedSignedCookieSalt), 64))
+	case GCM:
+		return DecryptGCM(cookie, key.deriveKey(orDefault(key.AuthenticatedSalt, AuthenticatedEncryptedCookieSalt), 32))
+	}
+	return nil, ErrInvalidCookie
+}
+
+// DetectCipher guesses the cipher of an unescaped cookie value from its shape:
+// GCM cookies have three "--" separated parts and CBC cookies have two.
+func DetectCipher(cookie string) Cipher {
+	switch strings.Count(cookie, "--") {
+	case 1:
+		return CBC
+	case 2:
+		return GCM
+	}
+	return ""
+}
+
+type derivedKeyID struct {
+	secret, salt string
+	size         int
+	digest       Digest
+}
+
+// derivedKeys caches the keys like ActiveSupport::CachingKeyGenerator, so
+// PBKDF2 runs once per secret and salt instead of on each cookie.
+var derivedKeys = struct {
+	sync.Mutex
+	m map[derivedKeyID][]byte
+}{m: map[derivedKeyID][]byte{}}
+
+// deriveKey is the equivalent of ActiveSupport::KeyGenerator#generate_key.
+func (k Key) deriveKey(salt string, size int) []byte {
+	id := derivedKeyID{k.SecretKeyBase, salt, size, k.Digest}
+	derivedKeys.Lock()
+	defer derivedKeys.Unlock()
+	if key, ok := derivedKeys.m[id]; ok {
+		return key
+	}
+	key := pbkdf2.Key([]byte(k.SecretKeyBase), []byte(salt), keyIterations, size, k.Digest.hash())
+	derivedKeys.m[id] = key
+	return key
+}
+
+func (d Digest) hash() func() hash.Hash {
+	if d == SHA256 {
+		return sha256.New
+	}
+	return sha1.New
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// The cookies of the tests are known-answer vectors: they were generated
+// outside of Go with the test secret_key_base of the example app, following
+// ActiveSupport's KeyGenerator, MessageEncryptor and MessageVerifier, with
+// fixed IVs instead of random ones.
+
+const testSecret = "878e11813d3639469f8210eca791b47446de2b805fb2c3fd1bd7444ef9f3c5295f4e72fae40ae469bc864ae0ee32fe15ce3dee2d5d008745e092c7db2d152e3d"
+
+const testCookieName = "_example_read_rails_session_session"
+
+// the session of the cookies, as written by the JSON serializer
+const testSessionJSON = `{"session_id":"a3b3b33fc3336a5e29c99bbc09db714d","_csrf_token":"Qq4w6t9W4jUIqsAKJ6AkrIW0oJWMllMwaxQrgTTZ0+8=","flash":{"discard":[],"flashes":{"notice":"Welcome! You have signed up successfully."}},"warden.user.user.key":[[2],"$2a$11$hGNO577ObqIlHtD/cMMRH."]}`
+
+const (
+	// Rails 5.2, GCM with a SHA1 key and no metadata
+	gcmCookie = "cNIPSeAU4xtb0zRzYAHh456NQrPuLAR%2B8oPyieDJoT7KhWnP%2FS5IkhUr8udGxwGUynRQu7fEDu%2Fk%2FIFpLBl6BIWSdOlGjbWILrgJ5JwxwzB0897RcsPMMgqAiEd4aS9s91DFbkBQKZUAmn6ZxHK749VfSNK0KxIw3Fy%2BFNC92rNLCCk3vTXzu9Jf%2FWYIUvNg6hPOhhdNhtKv8k7zh1B4gc%2F%2BZPy34pXfVwma7BR046AsN5xkKgvzqQORrK1%2Bvkn3ZQKXq01vSFl2qHs5sRgvhSn01NFtg%2Fas4MgNNk%2BIsxfJoCMGkgVkO8DFTHtsJP4iqoWOeXPzCbQxv%2BsyGv5X4ioIlQ%3D%3D--AQIDBAUGBwgJCgsM--GCFGQW3ccQDO36Qnuy6ugg%3D%3D"
+	// Rails 4.0 ~ 5.1, CBC + HMAC-SHA1 with the JSON serializer
+	cbcCookie = "dVNaNWg0WStOdm50NG9LY1h0NmkyRUttS3lRM1NsRGRacEhrWkRGRy9WVStMUDlFYUxHVHEycnluZCttSjRReDBiRk94eWM1c1R2YzNZVlJ0VWJLaENzSkQ0VVFGVk9yMVAxZXNablBURWpDek53eTRMRS9QSXNXRmVDdSsrcjFUdjdHOVdzWEV2UVgyRzhaQ1hpR2ZKcW4rUFk1aHJ1VUVlU0hvSUNTTnZGTEQxOGF2Yk5lZVpqQ2pYOHR5SG5TUDdDQTZMQWdCNFI5YUxQZkhnM0lyVU9SNk5icXNubytjSVl6SWk1MFNLQnFlMTV3SUhVeTl6UXB2ZHFkV0dCZGRKRlEyaC80T3hPMjZZbGUzQm04TjQ1S3FrTWg3QSs4QkdvcXBjdFl1OVdUWlpFNk5WVWVjRFJTY0d3TWU5Q1dxZVUrOU9Xc0hERGp2VkVIdk9SNzJCZENGN0U5ZjFOVXFzRkdiaW1Zd3FVPS0tQVFJREJBVUdCd2dKQ2dzTURRNFBFQT09--77273d8049c6b8e29046c3848e67997b854d5ab4"
+)
+
+func TestDecrypt(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		cipher Cipher
+	}{
+		{"gcm", gcmCookie, ""},
+		{"cbc", cbcCookie, ""},
+		{"gcm forced", gcmCookie, GCM},
+		{"cbc forced", cbcCookie, CBC},
+	}
+	for _, tt := range tests {
+		data, err := Decrypt(tt.cookie, Key{SecretKeyBase: testSecret, Cipher: tt.cipher})
+		if err != nil {
+			t.Errorf("%s: Decrypt err: %v", tt.name, err)
+			continue
+		}
+		if string(data) != testSessionJSON {
+			t.Errorf("%s: Decrypt = %s, want %s", tt.name, data, testSessionJSON)
+		}
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	key := Key{SecretKeyBase: testSecret}
+	tests := []struct {
+		name   string
+		cookie string
+		key    Key
+		err    error
+	}{
+		{"gcm other secret", gcmCookie, Key{SecretKeyBase: strings.Repeat("0", 128)}, ErrInvalidSignature},
+		{"gcm sha256 key", gcmCookie, Key{SecretKeyBase: testSecret, Digest: SHA256}, ErrInvalidSignature},
+		{"gcm tampered data", tamper(gcmCookie, 0), key, ErrInvalidSignature},
+		{"gcm tampered tag", strings.Replace(gcmCookie, "--GCFG", "--GCFH", 1), key, ErrInvalidSignature},
+		{"gcm short tag", gcmCookie[:strings.LastIndex(gcmCookie, "--")] + "--AAAA", key, ErrInvalidCookie},
+		{"cbc other secret", cbcCookie, Key{SecretKeyBase: strings.Repeat("0", 128)}, ErrInvalidSignature},
+		{"cbc tampered data", tamper(cbcCookie, 0), key, ErrInvalidSignature},
+		{"cbc tampered hmac", cbcCookie[:len(cbcCookie)-1] + "5", key, ErrInvalidSignature},
+		{"cbc hmac not hex", cbcCookie[:len(cbcCookie)-1] + "z", key, ErrInvalidCookie},
+		{"gcm as cbc", gcmCookie, Key{SecretKeyBase: testSecret, Cipher: CBC}, ErrInvalidCookie},
+		{"cbc as gcm", cbcCookie, Key{SecretKeyBase: testSecret, Cipher: GCM}, ErrInvalidCookie},
+		{"no separator", "abc", key, ErrInvalidCookie},
+		{"bad escape", "%zz", key, ErrInvalidCookie},
+		{"empty", "", key, ErrInvalidCookie},
+	}
+	for _, tt := range tests {
+		if _, err := Decrypt(tt.cookie, tt.key); err != tt.err {
+			t.Errorf("%s: Decrypt err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+// tamper flips a bit of the character at i, keeping it a base64 character.
+func tamper(cookie string, i int) string {
+	b := []byte(cookie)
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+func TestDetectCipher(t *testing.T) {
+	tests := []struct {
+		cookie string
+		want   Cipher
+	}{
+		{"data--iv--tag", GCM},
+		{"data--hmac", CBC},
+		{"data", ""},
+		{"a--b--c--d", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectCipher(tt.cookie); got != tt.want {
+			t.Errorf("DetectCipher(%q) = %q, want %q", tt.cookie, got, tt.want)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	// PBKDF2-HMAC-SHA1 of the key generator, 1000 iterations
+	want := "f3967df9553c783924320daba393aac8b6acfe65b41fd3fefd2ab12d9cf3e530"
+	key := Key{SecretKeyBase: testSecret}.deriveKey(AuthenticatedEncryptedCookieSalt, 32)
+	if hex.EncodeToString(key) != want {
+		t.Fatalf("deriveKey = %x, want %s", key, want)
+	}
+	if sha256Key := (Key{SecretKeyBase: testSecret, Digest: SHA256}).deriveKey(AuthenticatedEncryptedCookieSalt, 32); bytes.Equal(key, sha256Key) {
+		t.Errorf("the SHA1 and SHA256 keys are the same")
+	}
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Signed cookies, `cookies.signed` in Rails, aren't encrypted: they're written
+// by ActiveSupport::MessageVerifier as base64(data)--hex(hmac), e.g. Devise's
+// remember_user_token.
+
+// Verify checks the signature of a signed cookie value with the key and
+// returns the serialized data.
+func Verify(cookie string, key Key) ([]byte, error) {
+	cookie, err := url.QueryUnescape(cookie)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	i := strings.LastIndex(cookie, "--")
+	if i <= 0 {
+		return nil, ErrInvalidCookie
+	}
+	data, digest := cookie[:i], cookie[i+2:]
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	if !hmac.Equal(key.sign(data), expected) {
+		return nil, ErrInvalidSignature
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	return decoded, nil
+}
+
+// Sign is the inverse of Verify, it returns the escaped signed cookie value of the data.
+func Sign(data []byte, key Key) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	return url.QueryEscape(encoded + "--" + hex.EncodeToString(key.sign(encoded)))
+}
+
+func (k Key) sign(data string) []byte {
+	mac := hmac.New(k.SignedDigest.hash(), k.deriveKey(orDefault(k.SignedSalt, SignedCookieSalt), 64))
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Verify tries the keys in order like Decrypt, for signed cookies.
+func (kr *Keyring) Verify(cookie string) ([]byte, *Match, error) {
+	err := ErrInvalidSignature
+	for i, key := range kr.Keys {
+		data, e := Verify(cookie, key)
+		if e == ErrInvalidCookie {
+			return nil, nil, e
+		}
+		if e != nil {
+			err = e
+			continue
+		}
+		m := &Match{Index: i, Key: key}
+		if m.Deprecated() && kr.OnDeprecated != nil {
+			kr.OnDeprecated(*m)
+		}
+		return data, m, nil
+	}
+	return nil, nil, err
+}
+
+// Sign signs data with the current key.
+func (kr *Keyring) Sign(data []byte) (string, error) {
+	if len(kr.Keys) == 0 {
+		return "", errors.New("session: empty keyring")
+	}
+	return Sign(data, kr.Keys[0]), nil
+}
+
+// SignedJar reads and writes the signed cookies of a Rails app, with the same
+// serializer and metadata settings as the session cookie.
+type SignedJar struct {
+	Keyring    *Keyring
+	Serializer Serializer
+	Metadata   bool
+}
+
+// Decode verifies the value of the signed cookie with the name and returns its data as JSON.
+func (j *SignedJar) Decode(name, value string) ([]byte, error) {
+	data, _, err := j.Keyring.Verify(value)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err = Unwrap(data, Purpose(name))
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(data, j.Serializer)
+}
+
+// Encode serializes and signs the JSON data as the value of the signed cookie
+// with the name, the expiry is embedded in the metadata unless it's zero.
+func (j *SignedJar) Encode(name string, data []byte, expires time.Time) (string, error) {
+	data, err := Serialize(data, j.Serializer)
+	if err != nil {
+		return "", err
+	}
+	if j.Metadata {
+		if data, err = Wrap(data, Purpose(name), expires); err != nil {
+			return "", err
+		}
+	}
+	return j.Keyring.Sign(data)
+}
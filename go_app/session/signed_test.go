@@ -0,0 +1,106 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+// Devise's remember_user_token of the user 2, signed by the MessageVerifier of
+// cookies.signed with testSecret.
+const (
+	// Rails 5.x, no metadata
+	signedRememberCookie = "W1syXSwiJDJhJDExJGhHTk81NzdPYnFJbEh0RC9jTU1SSC4iLCIxNzAwMDAwMDAwLjEyMzQ1NiJd--8429622fe94b0e423e57d2e96029acfea9e3c22d"
+	// Rails 6.0 with the metadata, expiring in 2033
+	signedRememberMetadataCookie = "eyJfcmFpbHMiOnsibWVzc2FnZSI6Ilcxc3lYU3dpSkRKaEpERXhKR2hIVGs4MU56ZFBZbkZKYkVoMFJDOWpUVTFTU0M0aUxDSXhOekF3TURBd01EQXdMakV5TXpRMU5pSmQiLCJleHAiOiIyMDMzLTExLTE0VDIyOjEzOjIwLjAwMFoiLCJwdXIiOiJjb29raWUucmVtZW1iZXJfdXNlcl90b2tlbiJ9fQ%3D%3D--3a32fa9cb1b8723882c2683346ae19c2d125059a"
+	// the same expired in 2020
+	signedRememberExpiredCookie = "eyJfcmFpbHMiOnsibWVzc2FnZSI6Ilcxc3lYU3dpSkRKaEpERXhKR2hIVGs4MU56ZFBZbkZKYkVoMFJDOWpUVTFTU0M0aUxDSXhOekF3TURBd01EQXdMakV5TXpRMU5pSmQiLCJleHAiOiIyMDIwLTAxLTAxVDAwOjAwOjAwLjAwMFoiLCJwdXIiOiJjb29raWUucmVtZW1iZXJfdXNlcl90b2tlbiJ9fQ%3D%3D--3ca26075b4f90274fa3e413eec404054957cb47b"
+	// the same written as the session cookie
+	signedSessionPurposeCookie = "eyJfcmFpbHMiOnsibWVzc2FnZSI6Ilcxc3lYU3dpSkRKaEpERXhKR2hIVGs4MU56ZFBZbkZKYkVoMFJDOWpUVTFTU0M0aUxDSXhOekF3TURBd01EQXdMakV5TXpRMU5pSmQiLCJleHAiOiIyMDMzLTExLTE0VDIyOjEzOjIwLjAwMFoiLCJwdXIiOiJjb29raWUuX2V4YW1wbGVfcmVhZF9yYWlsc19zZXNzaW9uX3Nlc3Npb24ifX0%3D--f26b23ac101a51427f7749e491116e1178d7b520"
+	// SHA256 key generator and HMAC, no metadata
+	signedRememberSHA256Cookie = "W1syXSwiJDJhJDExJGhHTk81NzdPYnFJbEh0RC9jTU1SSC4iLCIxNzAwMDAwMDAwLjEyMzQ1NiJd--f4c724437558c02b521af21cc80ecc40df00a57b04e63604857dea00ffe6e777"
+
+	signedRememberJSON = `[[2],"$2a$11$hGNO577ObqIlHtD/cMMRH.","1700000000.123456"]`
+)
+
+func TestSignedJarRemember(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    Key
+		cookie string
+	}{
+		{"rails 5", Key{SecretKeyBase: testSecret}, signedRememberCookie},
+		{"metadata", Key{SecretKeyBase: testSecret}, signedRememberMetadataCookie},
+		{"sha256", Key{SecretKeyBase: testSecret, Digest: SHA256, SignedDigest: SHA256}, signedRememberSHA256Cookie},
+	}
+	name := RememberCookieName("user")
+	for _, tt := range tests {
+		jar := &SignedJar{Keyring: NewKeyring(tt.key), Serializer: JSONSerializer}
+		data, err := jar.Decode(name, tt.cookie)
+		if err != nil {
+			t.Errorf("%s: Decode err: %v", tt.name, err)
+			continue
+		}
+		if string(data) != signedRememberJSON {
+			t.Errorf("%s: Decode = %s, want %s", tt.name, data, signedRememberJSON)
+		}
+		token, err := ParseRememberToken(data)
+		if err != nil {
+			t.Errorf("%s: ParseRememberToken err: %v", tt.name, err)
+			continue
+		}
+		if token.ID != 2 || token.Token != "$2a$11$hGNO577ObqIlHtD/cMMRH." || token.GeneratedAt.Unix() != 1700000000 {
+			t.Errorf("%s: token = %+v", tt.name, token)
+		}
+	}
+}
+
+func TestSignedJarRejected(t *testing.T) {
+	name := RememberCookieName("user")
+	tampered := signedRememberCookie[:len(signedRememberCookie)-1] + "e"
+	tests := []struct {
+		name   string
+		key    Key
+		cookie string
+		err    error
+	}{
+		{"tampered digest", Key{SecretKeyBase: testSecret}, tampered, ErrInvalidSignature},
+		{"tampered data", Key{SecretKeyBase: testSecret}, tamper(signedRememberCookie, 3), ErrInvalidSignature},
+		{"other secret", Key{SecretKeyBase: oldTestSecret}, signedRememberCookie, ErrInvalidSignature},
+		{"sha1 cookie with sha256", Key{SecretKeyBase: testSecret, SignedDigest: SHA256}, signedRememberCookie, ErrInvalidSignature},
+		{"expired", Key{SecretKeyBase: testSecret}, signedRememberExpiredCookie, ErrExpired},
+		{"session cookie", Key{SecretKeyBase: testSecret}, signedSessionPurposeCookie, ErrPurposeMismatch},
+		{"no digest", Key{SecretKeyBase: testSecret}, "W1syXSwiJDJhJDExJGhHTk81NzdPYnFJbEh0RC9jTU1SSC4iLCIxNzAwMDAwMDAwLjEyMzQ1NiJd", ErrInvalidCookie},
+		{"digest not hex", Key{SecretKeyBase: testSecret}, "W1syXQ==--zz", ErrInvalidCookie},
+	}
+	for _, tt := range tests {
+		jar := &SignedJar{Keyring: NewKeyring(tt.key), Serializer: JSONSerializer}
+		if _, err := jar.Decode(name, tt.cookie); err != tt.err {
+			t.Errorf("%s: Decode err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSignedJarEncode(t *testing.T) {
+	name := RememberCookieName("user")
+	jar := &SignedJar{Keyring: NewKeyring(Key{SecretKeyBase: testSecret}), Serializer: JSONSerializer}
+	cookie, err := jar.Encode(name, []byte(signedRememberJSON), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without metadata Rails writes the same cookie
+	if cookie != signedRememberCookie {
+		t.Errorf("Encode = %s, want %s", cookie, signedRememberCookie)
+	}
+
+	jar.Metadata = true
+	cookie, err = jar.Encode(name, []byte(signedRememberJSON), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := jar.Decode(name, cookie); err != nil || string(data) != signedRememberJSON {
+		t.Errorf("Decode(Encode) = %s, %v", data, err)
+	}
+	if _, err := jar.Decode("remember_admin_token", cookie); err != ErrPurposeMismatch {
+		t.Errorf("Decode as another cookie err = %v, want %v", err, ErrPurposeMismatch)
+	}
+}
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLBackend stores the sessions in the table of activerecord-session_store,
+// `sessions` with the `session_id` and `data` columns. The Marshal sessions
+// are base64 encoded in the data column, the JSON ones are stored as is.
+type SQLBackend struct {
+	DB    *sqlx.DB
+	Table string
+}
+
+// NewSQLBackend returns a backend for the `sessions` table of the database.
+func NewSQLBackend(db *sqlx.DB) *SQLBackend {
+	return &SQLBackend{DB: db, Table: "sessions"}
+}
+
+func (b *SQLBackend) Get(id string) ([]byte, error) {
+	var data string
+	err := b.DB.Get(&data, b.DB.Rebind(`SELECT data FROM `+b.Table+` WHERE session_id = ?`), id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw := []byte(data)
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return raw, nil
+	}
+	// Ruby's pack("m") breaks the lines, StdEncoding doesn't accept them
+	raw, err = base64.StdEncoding.DecodeString(string(bytes.Replace(raw, []byte("\n"), nil, -1)))
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	return raw, nil
+}
+
+// Set inserts the session or updates it in one statement on MySQL and
+// PostgreSQL, so two first writes of the same session don't both insert it.
+// SQLite before 3.24 has no upsert, the row is inserted unless it exists and
+// then updated in a transaction. Both rely on the unique index on session_id
+// of the activerecord-session_store migration. The ttl is ignored, like Rails
+// the old sessions are left to be cleaned up by `rails db:sessions:trim`.
+func (b *SQLBackend) Set(id string, data []byte, ttl time.Duration) error {
+	value := string(data)
+	if IsMarshal(data) {
+		value = base64.StdEncoding.EncodeToString(data)
+	}
+	now := time.Now().UTC()
+	if query := upsertSQL(b.DB.DriverName(), b.Table); query != "" {
+		_, err := b.DB.Exec(b.DB.Rebind(query), id, value, now, now)
+		return err
+	}
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO `+b.Table+` (session_id, data, created_at, updated_at) VALUES (?, ?, ?, ?)`, id, value, now, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE `+b.Table+` SET data = ?, updated_at = ? WHERE session_id = ?`, value, now, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// upsertSQL returns the INSERT of a session that updates an existing one, or
+// "" when the database has none.
+func upsertSQL(driver, table string) string {
+	insert := `INSERT INTO ` + table + ` (session_id, data, created_at, updated_at) VALUES (?, ?, ?, ?)`
+	switch driver {
+	case "mysql":
+		return insert + ` ON DUPLICATE KEY UPDATE data = VALUES(data), updated_at = VALUES(updated_at)`
+	case "postgres":
+		return insert + ` ON CONFLICT (session_id) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at`
+	}
+	return ""
+}
+
+func (b *SQLBackend) Delete(id string) error {
+	_, err := b.DB.Exec(b.DB.Rebind(`DELETE FROM `+b.Table+` WHERE session_id = ?`), id)
+	return err
+}
@@ -0,0 +1,94 @@
+package session
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/railstack/go-sqlite3"
+)
+
+// testSQLBackend returns a backend on an in-memory SQLite database with the
+// sessions table of activerecord-session_store.
+func testSQLBackend(t *testing.T) *SQLBackend {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+	schema := `CREATE TABLE sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id VARCHAR NOT NULL,
+		data TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	CREATE UNIQUE INDEX index_sessions_on_session_id ON sessions (session_id);`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQLBackend(db)
+}
+
+func TestSQLBackend(t *testing.T) {
+	b := testSQLBackend(t)
+	defer b.DB.Close()
+	id := "a3b3b33fc3336a5e29c99bbc09db714d"
+
+	// concurrent first writes of the session insert it once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Set(id, []byte(testSessionJSON), 0); err != nil {
+				t.Errorf("Set err: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	var count int
+	if err := b.DB.Get(&count, `SELECT COUNT(*) FROM sessions WHERE session_id = ?`, id); err != nil || count != 1 {
+		t.Fatalf("session rows = %d, %v, want 1", count, err)
+	}
+
+	marshal, err := Serialize([]byte(testSessionJSON), MarshalSerializer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Set(id, marshal, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.Get(id); err != nil || string(got) != string(marshal) {
+		t.Errorf("Get of the Marshal session = %q, %v", got, err)
+	}
+	var raw string
+	if err := b.DB.Get(&raw, `SELECT data FROM sessions WHERE session_id = ?`, id); err != nil || strings.HasPrefix(raw, "\x04\x08") {
+		t.Errorf("data column = %q, %v, want base64", raw, err)
+	}
+
+	if err := b.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.Get(id); got != nil || err != nil {
+		t.Errorf("Get after Delete = %q, %v", got, err)
+	}
+}
+
+func TestUpsertSQL(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", "INSERT INTO sessions (session_id, data, created_at, updated_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data), updated_at = VALUES(updated_at)"},
+		{"postgres", "INSERT INTO sessions (session_id, data, created_at, updated_at) VALUES (?, ?, ?, ?) ON CONFLICT (session_id) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at"},
+		{"sqlite3", ""},
+	}
+	for _, tt := range tests {
+		if got := upsertSQL(tt.driver, "sessions"); got != tt.want {
+			t.Errorf("upsertSQL(%s) = %q, want %q", tt.driver, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SessionStore loads and saves the Rails sessions of the requests, like the
+// `config.session_store` of Rails. CookieStore keeps the whole session in the
+// cookie, ServerStore keeps it in a database and the cookie only holds its id.
+type SessionStore interface {
+	// Load reads the session of a request, a request without a session gets a
+	// new empty one.
+	Load(r *http.Request) (*Session, error)
+	// Save writes the session of the request, it must be called before the
+	// response body is written.
+	Save(w http.ResponseWriter, r *http.Request, s *Session) error
+}
+
+// Backend is the database of a ServerStore, e.g. SQLBackend or RedisBackend.
+type Backend interface {
+	// Get returns the serialized data of a session id, or nil if there's none.
+	Get(id string) ([]byte, error)
+	// Set stores the data of a session id, expiring after ttl if it's not 0.
+	Set(id string, data []byte, ttl time.Duration) error
+	Delete(id string) error
+}
+
+// ServerStore keeps the sessions in a backend, like activerecord-session_store
+// and redis-session-store. The cookie holds the session id, it's signed only
+// if SignedJar is set.
+type ServerStore struct {
+	Backend    Backend
+	Serializer Serializer
+	Options    CookieOptions
+	SignedJar  *SignedJar
+}
+
+// PrivateID returns the id a session is stored under since Rack 2.0.9, so the
+// ids in the database can't be used as cookies: "2::" and the SHA256 of the id.
+func PrivateID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "2::" + hex.EncodeToString(sum[:])
+}
+
+func (st *ServerStore) Load(r *http.Request) (*Session, error) {
+	id, err := st.sessionID(r)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return New(nil), nil
+	}
+	data, err := st.Backend.Get(PrivateID(id))
+	if err == nil && data == nil {
+		// stored by an older version with the public id
+		data, err = st.Backend.Get(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		// an unknown or expired session, Rack starts a new one
+		return New(nil), nil
+	}
+	data, err = Deserialize(data, st.Serializer)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	s.values["session_id"] = id
+	s.exists = true
+	s.loadedID = id
+	return s, nil
+}
+
+func (st *ServerStore) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	id, _ := s.values["session_id"].(string)
+	if id == "" {
+		id = NewSessionID()
+		s.values["session_id"] = id
+	}
+	if s.loadedID != "" && s.loadedID != id {
+		// a renewed or reset session, the old one must not be usable anymore
+		if err := st.Backend.Delete(PrivateID(s.loadedID)); err != nil {
+			return err
+		}
+		if err := st.Backend.Delete(s.loadedID); err != nil {
+			return err
+		}
+	}
+
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		if k != "session_id" {
+			values[k] = v
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if data, err = Serialize(data, st.Serializer); err != nil {
+		return err
+	}
+	if err := st.Backend.Set(PrivateID(id), data, st.Options.ExpireAfter); err != nil {
+		return err
+	}
+
+	if id != s.loadedID || st.Options.ExpireAfter > 0 {
+		value := id
+		if st.SignedJar != nil {
+			idJSON, _ := json.Marshal(id)
+			if value, err = st.SignedJar.Encode(st.Options.Name, idJSON, time.Time{}); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, st.Options.cookie(r, value))
+	}
+	s.loadedID = id
+	return nil
+}
+
+// sessionID returns the session id of the request cookie, or "" without a valid one.
+func (st *ServerStore) sessionID(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(st.Options.Name)
+	if err != nil {
+		return "", nil
+	}
+	if st.SignedJar == nil {
+		return cookie.Value, nil
+	}
+	data, err := st.SignedJar.Decode(st.Options.Name, cookie.Value)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	if err := json.Unmarshal(data, &id); err != nil {
+		return "", ErrInvalidCookie
+	}
+	return id, nil
+}
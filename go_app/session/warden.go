@@ -0,0 +1,85 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrNoWardenUser is returned when no user of the scope is signed in.
+var ErrNoWardenUser = errors.New("session: no warden user")
+
+// WardenUser is a user signed in with Devise, serialized by Warden in the
+// session under `warden.user.<scope>.key` as [[id], authenticatable_salt].
+type WardenUser struct {
+	Scope string `json:"scope"`
+	ID    int64  `json:"id"`
+	Salt  string `json:"salt"`
+}
+
+// WardenKey returns the session key of the Warden user of the Devise scope, e.g. "user".
+func WardenKey(scope string) string {
+	return "warden.user." + scope + ".key"
+}
+
+// WardenScopes returns the Devise scopes a user is signed in for.
+func (s *Session) WardenScopes() []string {
+	var scopes []string
+	for k := range s.values {
+		if strings.HasPrefix(k, "warden.user.") && strings.HasSuffix(k, ".key") {
+			scopes = append(scopes, strings.TrimSuffix(strings.TrimPrefix(k, "warden.user."), ".key"))
+		}
+	}
+	return scopes
+}
+
+// WardenUser decodes the Warden user of the Devise scope. ErrNoWardenUser is
+// returned when none is signed in, and an error if the value isn't in the
+// Devise format.
+func (s *Session) WardenUser(scope string) (*WardenUser, error) {
+	v, ok := s.values[WardenKey(scope)]
+	if !ok || v == nil {
+		return nil, ErrNoWardenUser
+	}
+	path := childPath("$", WardenKey(scope))
+	record, ok := v.([]interface{})
+	if !ok || len(record) != 2 {
+		return nil, &DecodeError{Path: path, Expected: "[[id], salt]", Got: kindOf(v)}
+	}
+	ids, ok := record[0].([]interface{})
+	if !ok || len(ids) != 1 {
+		return nil, &DecodeError{Path: path + "[0]", Expected: "[id]", Got: kindOf(record[0])}
+	}
+	id, err := toInt64(ids[0])
+	if err != nil {
+		return nil, &DecodeError{Path: path + "[0][0]", Expected: "int64", Got: kindOf(ids[0])}
+	}
+	// the salt is nil for models without a password
+	salt, _ := record[1].(string)
+	return &WardenUser{Scope: scope, ID: id, Salt: salt}, nil
+}
+
+// SetWardenUser signs a user in for the Devise scope, as Warden's set_user does.
+func (s *Session) SetWardenUser(u WardenUser) {
+	s.Set(WardenKey(u.Scope), []interface{}{[]interface{}{u.ID}, u.Salt})
+}
+
+// DeleteWardenUser signs the user of the Devise scope out.
+func (s *Session) DeleteWardenUser(scope string) {
+	s.Delete(WardenKey(scope))
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.Int64()
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, errors.New("session: warden user id isn't an integer")
+}